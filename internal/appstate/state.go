@@ -2,6 +2,7 @@ package appstate
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 )
 
@@ -12,6 +13,15 @@ type State struct {
 
 	model        string
 	systemPrompt string
+	showThinking bool
+
+	// Generation parameters using Ollama names, e.g. "temperature"
+	params map[string]any
+
+	// JSON output mode; schema is optional
+	jsonMode       bool
+	jsonSchemaPath string
+	jsonSchema     map[string]any
 
 	// What about History? should I keep it here?
 }
@@ -43,6 +53,13 @@ func WithSystemPrompt(systemPrompt string) Option {
 	}
 }
 
+func WithShowThinking(show bool) Option {
+	return func(s *State) error {
+		s.showThinking = show
+		return nil
+	}
+}
+
 // GetModel returns current model set
 func (s *State) GetModel() string {
 	s.mu.RLock()
@@ -72,3 +89,64 @@ func (s *State) SetSystemPrompt(prompt string) {
 	s.systemPrompt = prompt
 	// TBD - this change can optionally be peristed to user preferences
 }
+
+// ShowThinking reports whether reasoning output is displayed while streaming
+func (s *State) ShowThinking() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.showThinking
+}
+
+// SetShowThinking shows or collapses reasoning output
+func (s *State) SetShowThinking(show bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.showThinking = show
+}
+
+// JSONMode reports whether JSON output mode is enabled and returns the schema
+// (and the file it was loaded from) used to validate answers, if any
+func (s *State) JSONMode() (bool, string, map[string]any) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jsonMode, s.jsonSchemaPath, s.jsonSchema
+}
+
+// EnableJSONMode turns on JSON output mode with an optional schema
+func (s *State) EnableJSONMode(schemaPath string, schema map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jsonMode = true
+	s.jsonSchemaPath = schemaPath
+	s.jsonSchema = schema
+}
+
+// DisableJSONMode turns off JSON output mode
+func (s *State) DisableJSONMode() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jsonMode = false
+	s.jsonSchemaPath = ""
+	s.jsonSchema = nil
+}
+
+// Params returns the generation parameters set for this session
+func (s *State) Params() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.params)
+}
+
+// SetParam sets a generation parameter; a nil value removes it
+func (s *State) SetParam(name string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.params, name)
+		return
+	}
+	if s.params == nil {
+		s.params = make(map[string]any)
+	}
+	s.params[name] = value
+}
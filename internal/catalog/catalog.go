@@ -0,0 +1,407 @@
+// Package catalog keeps track of the models of all configured hosts, Ollama
+// servers and other providers alike
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tchat/internal/ollama"
+	"tchat/internal/provider"
+
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// hostCheckTimeout limits how long listing the models of a host may take
+// before the host counts as unreachable
+const hostCheckTimeout = 5 * time.Second
+
+// Host is a named model backend. Its models are registered with Genkit as
+// "<name>/<model>", so models of different hosts can be told apart.
+type Host struct {
+	Name     string
+	Address  string
+	Provider provider.Provider
+}
+
+// IsOllama reports whether the host is an Ollama server
+func (h Host) IsOllama() bool {
+	_, ok := h.Provider.(*ollama.Provider)
+	return ok
+}
+
+// HostStatus describes the health of a host as of the last check
+type HostStatus struct {
+	Host
+	Healthy bool
+	Err     error // why the host is unreachable
+	Models  int   // number of available models
+}
+
+// Catalog holds the models registered with Genkit. It is shared by the
+// commands, so models registered while tchat runs are selectable at once.
+// Models of several hosts are told apart by the host name prefix.
+type Catalog struct {
+	g     *genkit.Genkit
+	hosts []*hostState // in configured order; the first is the primary host
+
+	// refreshMu serializes discovery, so models are defined with Genkit once
+	refreshMu sync.Mutex
+
+	mu      sync.RWMutex
+	removed map[string]bool // registered models no longer on their host
+}
+
+// hostState is what the catalog knows about one host
+type hostState struct {
+	Host
+	listed    bool // the models were listed at least once
+	healthy   bool
+	err       error
+	models    []string // chat model identifiers from the last listing
+	embedders []string // embedding model identifiers from the last listing
+}
+
+// ModelChanges describes how the available models changed in a refresh
+type ModelChanges struct {
+	Added     []string // models and embedders that are new on their host
+	Removed   []string // models and embedders that were deleted from their host
+	HostsDown []string // hosts that became unreachable
+	HostsUp   []string // hosts that are reachable again
+}
+
+// Empty reports whether nothing changed
+func (m ModelChanges) Empty() bool {
+	return len(m.Added) == 0 && len(m.Removed) == 0 && len(m.HostsDown) == 0 && len(m.HostsUp) == 0
+}
+
+// New creates an empty catalog for the given providers; there must be at
+// least one
+func New(g *genkit.Genkit, providers []provider.Provider) *Catalog {
+	c := &Catalog{
+		g:       g,
+		removed: make(map[string]bool),
+	}
+	for _, p := range providers {
+		c.hosts = append(c.hosts, &hostState{Host: Host{Name: p.Name(), Address: p.Address(), Provider: p}})
+	}
+	return c
+}
+
+// Primary returns the first configured host
+func (c *Catalog) Primary() Host {
+	return c.hosts[0].Host
+}
+
+// HostOf returns the host of a model identifier
+func (c *Catalog) HostOf(model string) (Host, bool) {
+	name, _ := ollama.SplitModel(model)
+	if h := c.host(name); h != nil {
+		return h.Host, true
+	}
+	return Host{}, false
+}
+
+// AddressOf returns the address of the host serving a model, or the
+// primary host's address if the model has no known host prefix
+func (c *Catalog) AddressOf(model string) string {
+	if host, ok := c.HostOf(model); ok {
+		return host.Address
+	}
+	return c.Primary().Address
+}
+
+// IsOllama reports whether a model is served by an Ollama host, so Ollama
+// features such as loading and unloading apply
+func (c *Catalog) IsOllama(model string) bool {
+	host, ok := c.HostOf(model)
+	return ok && host.IsOllama()
+}
+
+// Resolve splits a model name as typed by the user into its host and the
+// model name known to the host. Names without a host prefix belong to the
+// primary host.
+func (c *Catalog) Resolve(name string) (Host, string) {
+	if prefix, rest, ok := strings.Cut(name, "/"); ok {
+		if h := c.host(prefix); h != nil {
+			return h.Host, rest
+		}
+	}
+	return c.Primary(), name
+}
+
+// host finds a host by name
+func (c *Catalog) host(name string) *hostState {
+	for _, h := range c.hosts {
+		if h.Name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+// Hosts returns the status of all hosts as of the last check
+func (c *Catalog) Hosts() []HostStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	statuses := make([]HostStatus, 0, len(c.hosts))
+	for _, h := range c.hosts {
+		status := HostStatus{Host: h.Host, Healthy: h.healthy, Err: h.err}
+		if h.healthy {
+			status.Models = len(h.models)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// CheckHosts checks the health of all hosts concurrently. Models of
+// unreachable hosts are unavailable until the host answers again.
+func (c *Catalog) CheckHosts(ctx context.Context) []HostStatus {
+	errs := make([]error, len(c.hosts))
+	var wg sync.WaitGroup
+	for i, h := range c.hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, hostCheckTimeout)
+			defer cancel()
+			errs[i] = h.Provider.CheckHealth(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	for i, h := range c.hosts {
+		h.healthy, h.err = errs[i] == nil, errs[i]
+	}
+	c.mu.Unlock()
+	return c.Hosts()
+}
+
+// MarkUnreachable marks the host of a model as unreachable, e.g. after a
+// request failed to connect. The next refresh checks it again.
+func (c *Catalog) MarkUnreachable(model string, err error) {
+	name, _ := ollama.SplitModel(model)
+	h := c.host(name)
+	if h == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h.healthy {
+		slog.Warn("Host unreachable", "host", h.Name, "address", h.Address, "error", err)
+	}
+	h.healthy, h.err = false, err
+}
+
+// Online reports whether at least one host was reachable at the last check
+func (c *Catalog) Online() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, h := range c.hosts {
+		if h.healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// Models returns the identifiers of the registered models that are
+// available, grouped by host
+func (c *Catalog) Models() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var models []string
+	for _, h := range c.hosts {
+		if h.healthy {
+			models = append(models, h.models...)
+		}
+	}
+	return models
+}
+
+// Embedders returns the identifiers of the registered embedding models that
+// are available. They are defined as Genkit embedders, not as chat models.
+func (c *Catalog) Embedders() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var embedders []string
+	for _, h := range c.hosts {
+		if h.healthy {
+			embedders = append(embedders, h.embedders...)
+		}
+	}
+	return embedders
+}
+
+// Contains reports whether a model is registered and available
+func (c *Catalog) Contains(model string) bool {
+	return slices.Contains(c.Models(), model)
+}
+
+// SupportsMedia reports whether a model is defined with Genkit to accept
+// images, as its ModelSupports.Media says. Genkit rejects images for other
+// models.
+func (c *Catalog) SupportsMedia(model string) bool {
+	m, ok := genkit.LookupModel(c.g, model).(interface{ Desc() api.ActionDesc })
+	if !ok {
+		return false
+	}
+	modelMeta, _ := m.Desc().Metadata["model"].(map[string]any)
+	supports, _ := modelMeta["supports"].(map[string]any)
+	media, _ := supports["media"].(bool)
+	return media
+}
+
+// Removed returns the registered models that were removed from their host.
+// Genkit keeps their definitions, but they can no longer be used.
+func (c *Catalog) Removed() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	removed := make([]string, 0, len(c.removed))
+	for model := range c.removed {
+		removed = append(removed, model)
+	}
+	slices.Sort(removed)
+	return removed
+}
+
+// Discover lists the models of all hosts and registers them with Genkit.
+// Returns the available model identifiers; it fails only if no
+// host is reachable.
+func (c *Catalog) Discover() ([]string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.discover()
+	if err := c.unreachable(); err != nil {
+		return nil, err
+	}
+	return c.Models(), nil
+}
+
+// Refresh lists the models of all hosts again, registers new ones, marks
+// the ones that disappeared as removed and notes hosts that went down or
+// came back. It fails if no host is reachable.
+func (c *Catalog) Refresh() (ModelChanges, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	changes := c.discover()
+	if !changes.Empty() {
+		slog.Info("Model list changed", "added", changes.Added, "removed", changes.Removed,
+			"hosts_down", changes.HostsDown, "hosts_up", changes.HostsUp)
+	}
+	return changes, c.unreachable()
+}
+
+// unreachable returns why no host can be reached, nil if one can
+func (c *Catalog) unreachable() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var errs []error
+	for _, h := range c.hosts {
+		if h.healthy {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s (%s): %w", h.Name, h.Address, h.err))
+	}
+	if len(c.hosts) == 1 {
+		return c.hosts[0].err
+	}
+	return errors.Join(errs...)
+}
+
+// discover registers the models of all hosts concurrently and records what
+// changed
+func (c *Catalog) discover() ModelChanges {
+	lists := make([]provider.Models, len(c.hosts))
+	errs := make([]error, len(c.hosts))
+	var wg sync.WaitGroup
+	for i, h := range c.hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), hostCheckTimeout)
+			defer cancel()
+			lists[i], errs[i] = h.Provider.Register(ctx, c.g)
+		}()
+	}
+	wg.Wait()
+
+	var changes ModelChanges
+	for i, h := range c.hosts {
+		if errs[i] != nil {
+			slog.Warn("Failed to list models", "host", h.Name, "address", h.Address, "error", errs[i])
+			c.mu.Lock()
+			if h.healthy {
+				changes.HostsDown = append(changes.HostsDown, h.Name)
+			}
+			h.healthy, h.err = false, errs[i]
+			c.mu.Unlock()
+			continue
+		}
+
+		// Chat models and embedders are diffed alike
+		listed := slices.Concat(lists[i].Chat, lists[i].Embedders)
+		c.mu.Lock()
+		known := slices.Concat(h.models, h.embedders)
+		if !h.healthy && h.listed {
+			changes.HostsUp = append(changes.HostsUp, h.Name)
+		}
+		for _, model := range listed {
+			if h.listed && !slices.Contains(known, model) {
+				changes.Added = append(changes.Added, model)
+			}
+			delete(c.removed, model)
+		}
+		for _, model := range known {
+			if !slices.Contains(listed, model) {
+				changes.Removed = append(changes.Removed, model)
+				c.removed[model] = true
+			}
+		}
+		h.listed, h.healthy, h.err = true, true, nil
+		h.models, h.embedders = lists[i].Chat, lists[i].Embedders
+		c.mu.Unlock()
+	}
+	return changes
+}
+
+// Register fetches the details of a model of an Ollama host, defines it with
+// Genkit and adds it to the catalog. The name may have a host prefix;
+// without one the model is on the primary host. Returns the model
+// identifier and whether it is an embedding model.
+func (c *Catalog) Register(ctx context.Context, name string) (string, bool) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	host, name := c.Resolve(name)
+	p, ok := host.Provider.(*ollama.Provider)
+	if !ok {
+		return host.Name + "/" + name, false
+	}
+	model, embedding := p.RegisterModel(ctx, c.g, name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.host(host.Name)
+	if embedding {
+		if !slices.Contains(h.embedders, model) {
+			h.embedders = append(h.embedders, model)
+		}
+		delete(c.removed, model)
+		return model, true
+	}
+
+	if !slices.Contains(h.models, model) {
+		h.models = append(h.models, model)
+	}
+	delete(c.removed, model)
+	return model, false
+}
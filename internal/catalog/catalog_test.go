@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"tchat/internal/provider"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// fakeProvider serves a fixed list of models, or fails with err
+type fakeProvider struct {
+	name      string
+	models    []string
+	embedders []string
+	err       error
+}
+
+func (p *fakeProvider) Name() string    { return p.name }
+func (p *fakeProvider) Address() string { return "http://" + p.name }
+
+func (p *fakeProvider) CheckHealth(ctx context.Context) error { return p.err }
+
+func (p *fakeProvider) Register(ctx context.Context, g *genkit.Genkit) (provider.Models, error) {
+	if p.err != nil {
+		return provider.Models{}, p.err
+	}
+	var models provider.Models
+	for _, m := range p.models {
+		models.Chat = append(models.Chat, p.name+"/"+m)
+	}
+	for _, m := range p.embedders {
+		models.Embedders = append(models.Embedders, p.name+"/"+m)
+	}
+	return models, nil
+}
+
+func TestRefresh(t *testing.T) {
+	laptop := &fakeProvider{name: "laptop", models: []string{"llama3.2:3b", "qwen3:8b"}}
+	server := &fakeProvider{name: "server", models: []string{"gemma3:27b"}}
+	c := New(nil, []provider.Provider{laptop, server})
+
+	models, err := c.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(models, []string{"laptop/llama3.2:3b", "laptop/qwen3:8b", "server/gemma3:27b"}) {
+		t.Errorf("got models %v", models)
+	}
+
+	// A model is pulled, one is deleted and the server goes down
+	laptop.models = []string{"llama3.2:3b", "mistral:7b"}
+	server.err = errors.New("connection refused")
+	changes, err := c.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changes.Added, []string{"laptop/mistral:7b"}) ||
+		!slices.Equal(changes.Removed, []string{"laptop/qwen3:8b"}) ||
+		!slices.Equal(changes.HostsDown, []string{"server"}) || len(changes.HostsUp) != 0 {
+		t.Errorf("got changes %+v", changes)
+	}
+	if c.Contains("server/gemma3:27b") || !slices.Equal(c.Removed(), []string{"laptop/qwen3:8b"}) {
+		t.Errorf("got models %v, removed %v", c.Models(), c.Removed())
+	}
+
+	// The server comes back; its models are not reported as added
+	server.err = nil
+	changes, _ = c.Refresh()
+	if !slices.Equal(changes.HostsUp, []string{"server"}) || len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Errorf("got changes %+v", changes)
+	}
+	if !c.Contains("server/gemma3:27b") {
+		t.Errorf("models of the server are not available again")
+	}
+
+	// Nothing changed
+	if changes, _ := c.Refresh(); !changes.Empty() {
+		t.Errorf("got changes %+v", changes)
+	}
+
+	// An embedding model is pulled, then deleted
+	laptop.embedders = []string{"nomic-embed-text:latest"}
+	changes, _ = c.Refresh()
+	if !slices.Equal(changes.Added, []string{"laptop/nomic-embed-text:latest"}) || c.Contains("laptop/nomic-embed-text:latest") {
+		t.Errorf("got changes %+v, models %v", changes, c.Models())
+	}
+	laptop.embedders = nil
+	changes, _ = c.Refresh()
+	if !slices.Equal(changes.Removed, []string{"laptop/nomic-embed-text:latest"}) || len(c.Embedders()) != 0 {
+		t.Errorf("got changes %+v, embedders %v", changes, c.Embedders())
+	}
+
+	// No host reachable
+	laptop.err, server.err = errors.New("down"), errors.New("down")
+	if _, err := c.Refresh(); err == nil || c.Online() {
+		t.Errorf("refresh without reachable hosts succeeded")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	c := New(nil, []provider.Provider{&fakeProvider{name: "laptop"}, &fakeProvider{name: "server"}})
+	tests := []struct {
+		name, host, model string
+	}{
+		{"server/gemma3:27b", "server", "gemma3:27b"},
+		{"gemma3:27b", "laptop", "gemma3:27b"},
+		{"hf.co/user/model", "laptop", "hf.co/user/model"},
+	}
+	for _, tt := range tests {
+		host, model := c.Resolve(tt.name)
+		if host.Name != tt.host || model != tt.model {
+			t.Errorf("Resolve(%q) = %q, %q; want %q, %q", tt.name, host.Name, model, tt.host, tt.model)
+		}
+	}
+	if c.AddressOf("unknown/model") != "http://laptop" {
+		t.Errorf("models of unknown hosts are not on the primary host")
+	}
+}
+
+func TestSupportsMedia(t *testing.T) {
+	g := genkit.Init(context.Background())
+	generate := func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+		return nil, nil
+	}
+	genkit.DefineModel(g, "lm/llava", &ai.ModelOptions{Supports: &ai.ModelSupports{Media: true}}, generate)
+	genkit.DefineModel(g, "lm/qwen3", &ai.ModelOptions{Supports: &ai.ModelSupports{Multiturn: true}}, generate)
+	c := New(g, []provider.Provider{&fakeProvider{name: "lm"}})
+
+	if !c.SupportsMedia("lm/llava") {
+		t.Errorf("model defined with media support does not accept images")
+	}
+	if c.SupportsMedia("lm/qwen3") || c.SupportsMedia("lm/unknown") {
+		t.Errorf("models without media support accept images")
+	}
+}
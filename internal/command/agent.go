@@ -0,0 +1,209 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tchat/internal/db"
+	"tchat/internal/flows"
+	"tchat/internal/ollama"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/google/uuid"
+)
+
+// agentDoneMarker starts the final answer of an agent run
+const agentDoneMarker = "DONE:"
+
+// agentSystemPrompt drives the plan-act-observe loop
+const agentSystemPrompt = `You are an agent working towards a goal set by the user. Work in steps.
+In each step:
+1. Plan: say in one or two sentences what you will do next.
+2. Act: call the tools you need.
+3. Observe: check the tool results before deciding on the next step.
+Actions that change files need the user's approval; if the user declines, find another way or stop.
+When the goal is reached, or cannot be reached, reply with a line starting with "` + agentDoneMarker + `" followed by the final answer for the user.`
+
+// AgentCommand lets the model work towards a goal over several steps
+type AgentCommand struct {
+	store *db.Store
+}
+
+func NewAgentCommand(store *db.Store) *AgentCommand {
+	return &AgentCommand{
+		store: store,
+	}
+}
+
+func (c *AgentCommand) Name() string {
+	return "agent"
+}
+
+func (c *AgentCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *AgentCommand) Description() string {
+	return "Let the model use tools over several steps to reach a goal"
+}
+
+func (c *AgentCommand) Usage() string {
+	return "/agent <goal>"
+}
+
+func (c *AgentCommand) Execute(ctx *CommandContext) ExecutionResult {
+	goal := strings.TrimSpace(strings.Join(ctx.Args, " "))
+	if goal == "" {
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+	if ctx.Generate == nil {
+		ctx.Config.ErrorColor().Println("Generation is not available")
+		return REPLContinue
+	}
+	model := ctx.State.GetModel()
+	if !ollama.SupportsTools(model) {
+		ctx.Config.ErrorColor().Printf("%s does not support tools; switch to a model with the tools capability\n", model)
+		return REPLContinue
+	}
+	if jsonMode, _, _ := ctx.State.JSONMode(); jsonMode {
+		ctx.Config.ErrorColor().Println("Turn off JSON mode with /json off before running an agent")
+		return REPLContinue
+	}
+
+	// The transcript is stored as a session of its own
+	sessionID := uuid.NewString()
+	if c.store != nil {
+		if err := c.store.CreateSession(db.Session{
+			SessionId: sessionID,
+			Title:     agentTitle(goal),
+			ModelName: model,
+		}); err != nil {
+			slog.Error("Failed to create agent session", "error", err)
+		}
+	}
+
+	maxSteps := ctx.Config.AgentMaxSteps()
+	timeout := ctx.Config.AgentTimeout()
+	ctx.Config.InfoColor().Printf("🤖 Agent with %s: up to %d steps or %s. Press Ctrl-C to stop\n", model, maxSteps, timeout)
+	slog.Info("Agent started", "goal", goal, "model", model, "session_id", sessionID, "max_steps", maxSteps, "timeout", timeout)
+
+	runCtx, cancel := context.WithTimeout(ctx.Ctx, timeout)
+	defer cancel()
+
+	// Files referenced in the goal are attached to the first step
+	expanded, attachments, ok := AttachFiles(ctx, goal)
+	if !ok {
+		return REPLContinue
+	}
+
+	start := time.Now()
+	var history []*ai.Message
+	input := "Goal: " + expanded
+	answer, outcome := "", ""
+	done := false
+	toolCalls := 0
+	steps := 0
+	for !done && steps < maxSteps {
+		steps++
+		ctx.Config.InfoColor().Printf("\n── Step %d/%d (%s elapsed) ──\n", steps, maxSteps, time.Since(start).Round(time.Second))
+
+		req := flows.ChatRequest{
+			UserInput:    input,
+			Model:        model,
+			SystemPrompt: agentSystemPrompt,
+			History:      history,
+		}
+		stepStart := time.Now()
+		resp, err := ctx.Generate(runCtx, req)
+		step := NewTurn(0, req, resp, StatusOf(err))
+		if steps == 1 {
+			step.Input, step.Attachments = "Goal: "+goal, attachments
+		}
+		c.saveStep(sessionID, step, stepStart)
+
+		toolCalls += len(resp.ToolCalls)
+		ctx.Config.InfoColor().Printf("  • Step %d: %d tool call(s), %s\n", steps, len(resp.ToolCalls), time.Since(stepStart).Round(100*time.Millisecond))
+		// Later steps see what this one did and observed, not just its answer
+		history = append(history, ai.NewUserTextMessage(input))
+		history = append(history, resp.ToolMessages...)
+		history = append(history, ai.NewModelTextMessage(resp.Output))
+
+		answer = resp.Output
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				outcome = fmt.Sprintf("time budget of %s used up", timeout)
+			case errors.Is(err, context.Canceled):
+				outcome = "cancelled"
+			default:
+				outcome = "failed"
+			}
+			break
+		}
+		answer, done = finalAnswer(resp.Output)
+		input = "Continue with the next step towards the goal. If the goal is reached, reply with " + agentDoneMarker + " and the final answer."
+	}
+	if outcome == "" && !done {
+		outcome = fmt.Sprintf("step budget of %d used up", maxSteps)
+	}
+
+	duration := time.Since(start).Round(time.Second)
+	fmt.Println()
+	if outcome == "" {
+		ctx.Config.InfoColor().Printf("✓ Goal reached in %d step(s), %d tool call(s), %s\n", steps, toolCalls, duration)
+	} else {
+		ctx.Config.ErrorColor().Printf("⚠ Agent stopped after %d step(s), %d tool call(s), %s: %s\n", steps, toolCalls, duration, outcome)
+	}
+	if c.store != nil {
+		fmt.Printf("  Transcript saved as session %s\n", sessionID)
+	}
+	slog.Info("Agent finished", "session_id", sessionID, "steps", steps, "tool_calls", toolCalls,
+		"duration_ms", time.Since(start).Milliseconds(), "outcome", outcome)
+
+	// Keep the goal and final answer in the conversation for follow-up questions
+	if answer != "" {
+		ctx.History.AddUserMessage(model, expanded)
+		ctx.History.AddAssistantMessage(model, answer)
+		if ctx.LastResponse != nil {
+			*ctx.LastResponse = answer
+		}
+	}
+	return REPLContinue
+}
+
+// saveStep stores one step of the agent with its tool calls
+func (c *AgentCommand) saveStep(sessionID string, step Turn, startTime time.Time) {
+	id := saveTurn(c.store, sessionID, step, startTime)
+	toolCalls := step.Candidates[0].Response.ToolCalls
+	if id == 0 || len(toolCalls) == 0 {
+		return
+	}
+	if err := c.store.SaveToolCalls(id, ToDBToolCalls(toolCalls)); err != nil {
+		slog.Error("Failed to save tool calls to database", "error", err)
+	}
+}
+
+// finalAnswer returns the text after the done marker and whether the model
+// finished; unfinished output is returned as is
+func finalAnswer(output string) (string, bool) {
+	i := strings.Index(output, agentDoneMarker)
+	if i < 0 {
+		return output, false
+	}
+	return strings.TrimSpace(output[i+len(agentDoneMarker):]), true
+}
+
+// agentTitle names the session of an agent run
+func agentTitle(goal string) string {
+	const maxLen = 60
+	title := "Agent: " + goal
+	if runes := []rune(title); len(runes) > maxLen {
+		title = string(runes[:maxLen-3]) + "..."
+	}
+	return title
+}
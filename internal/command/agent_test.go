@@ -0,0 +1,18 @@
+package command
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAgentTitle(t *testing.T) {
+	if got := agentTitle("Summarize notes.md"); got != "Agent: Summarize notes.md" {
+		t.Errorf("agentTitle() = %q", got)
+	}
+
+	// Long goals are cut by characters, not bytes
+	got := agentTitle("Übersetze die Datei README.md ins Deutsche und prüfe die Überschriften")
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != 60 {
+		t.Errorf("agentTitle() = %q, %d characters", got, utf8.RuneCountInString(got))
+	}
+}
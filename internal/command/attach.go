@@ -0,0 +1,28 @@
+package command
+
+import (
+	"tchat/internal/parser"
+)
+
+// AttachFiles appends the files referenced with @path to user input, as the
+// model sees it, and reports them. References to missing files are sent as
+// text, with a warning if they look like files. Returns false if the input
+// must not be sent, e.g. because the attachments are too large.
+//
+// The expanded input goes into the request and history, so /retry and
+// /continue resend the files; turns store the input as typed along with the
+// attachments' paths and line ranges.
+func AttachFiles(ctx *CommandContext, input string) (string, []parser.Attachment, bool) {
+	expanded, attachments, missing, err := parser.Attach(input)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("✗ %v\n", err)
+		return "", nil, false
+	}
+	for _, ref := range missing {
+		ctx.Config.ErrorColor().Printf("⚠ @%s not found, sent as text\n", ref)
+	}
+	for _, a := range attachments {
+		ctx.Config.InfoColor().Printf("📎 Attached %s (%d lines)\n", a.Label(), a.Lines())
+	}
+	return expanded, attachments, true
+}
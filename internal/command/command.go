@@ -26,12 +26,18 @@ const (
 
 // CommandContext provides the runtime context for command execution
 type CommandContext struct {
-	Ctx          context.Context
-	Config       *config.Config
-	State        *appstate.State
-	Readline     *readline.Instance
-	History      *history.HistoryManager
-	LastResponse *string
+	Ctx           context.Context
+	Config        *config.Config
+	State         *appstate.State
+	Readline      *readline.Instance
+	History       *history.HistoryManager
+	LastResponse  *string
+	LastReasoning *string
+	SessionId     string                                                             // Chat session that new turns are stored in
+	LastTurn      *Turn                                                              // Last chat turn, for regeneration
+	Generate      GenerateFunc                                                       // Runs chat requests for commands that generate
+	Interruptible func(parent context.Context) (context.Context, context.CancelFunc) // Context that Ctrl-C cancels
+	Args          []string                                                           // Arguments following the command name
 }
 
 // Command represents a special command that can be executed in the REPL
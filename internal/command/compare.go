@@ -0,0 +1,192 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tchat/internal/catalog"
+	"tchat/internal/db"
+	"tchat/internal/flows"
+	"tchat/internal/media"
+)
+
+// CompareCommand sends the same prompt to several models and keeps one answer
+type CompareCommand struct {
+	store   *db.Store
+	catalog *catalog.Catalog
+}
+
+func NewCompareCommand(store *db.Store, catalog *catalog.Catalog) *CompareCommand {
+	return &CompareCommand{
+		store:   store,
+		catalog: catalog,
+	}
+}
+
+func (c *CompareCommand) Name() string {
+	return "compare"
+}
+
+func (c *CompareCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *CompareCommand) Description() string {
+	return "Send the same prompt to several models and keep one answer"
+}
+
+func (c *CompareCommand) Usage() string {
+	return "/compare model1,model2,... [prompt] - asks for the prompt if it is not given"
+}
+
+func (c *CompareCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if len(ctx.Args) == 0 {
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+	if ctx.Generate == nil {
+		ctx.Config.ErrorColor().Println("Generation is not available")
+		return REPLContinue
+	}
+
+	models, err := c.parseModels(ctx.Args[0])
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("%v\n", err)
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+
+	prompt := strings.Join(ctx.Args[1:], " ")
+	if prompt == "" {
+		prompt, err = ReadInputWithoutHistory("Prompt: ")
+		if err != nil || strings.TrimSpace(prompt) == "" {
+			fmt.Println("Comparison cancelled")
+			return REPLContinue
+		}
+	}
+
+	input, attachments, ok := AttachFiles(ctx, prompt)
+	if !ok {
+		return REPLContinue
+	}
+
+	base := flows.ChatRequest{
+		UserInput:    input,
+		SystemPrompt: ctx.State.GetSystemPrompt(),
+		History:      ctx.History.GetAll(),
+		ImagePaths:   media.ExtractImagePaths(prompt),
+		Options:      ctx.State.Params(),
+	}
+
+	// Models run one after another so each answer streams into its own panel
+	startTime := time.Now()
+	turn := Turn{Request: base, Input: prompt, Attachments: attachments}
+	for i, model := range models {
+		req := base
+		req.Model = model
+		ctx.Config.InfoColor().Printf("\n━━ [%d/%d] %s ━━\n", i+1, len(models), model)
+		// Genkit rejects images for models without vision; they answer the text
+		if len(req.ImagePaths) > 0 && !c.catalog.SupportsMedia(model) {
+			ctx.Config.ErrorColor().Printf("⚠ %s does not support images; sending the text only\n", model)
+			req.ImagePaths = nil
+		}
+
+		resp, err := ctx.Generate(ctx.Ctx, req)
+		if err != nil && resp.Output == "" {
+			if errors.Is(err, context.Canceled) {
+				break
+			}
+			continue
+		}
+		turn.Candidates = append(turn.Candidates, Candidate{
+			Model:    model,
+			Response: resp,
+			Status:   StatusOf(err),
+		})
+		if errors.Is(err, context.Canceled) {
+			// Ctrl-C stops the whole comparison
+			break
+		}
+	}
+
+	if len(turn.Candidates) == 0 {
+		ctx.Config.ErrorColor().Println("No answers to compare")
+		return REPLContinue
+	}
+
+	c.printSummary(ctx, turn)
+	turn.Selected = c.askSelection(len(turn.Candidates))
+
+	// The comparison becomes the last turn, so /pick can switch answers later
+	turn.Request.Model = turn.Candidates[turn.Selected].Model
+	turn.MsgId = saveTurn(c.store, ctx.SessionId, turn, startTime)
+	if turn.MsgId != 0 {
+		for i := range turn.Candidates {
+			if _, err := c.store.SaveCandidate(turn.toDBCandidate(i)); err != nil {
+				slog.Error("Failed to save candidate to database", "error", err)
+			}
+		}
+	}
+	*ctx.LastTurn = turn
+	keepSelected(ctx)
+
+	ctx.Config.InfoColor().Printf("✓ Keeping answer %d (%s). Use /pick N to choose another\n", turn.Selected+1, turn.Candidates[turn.Selected].Model)
+	return REPLContinue
+}
+
+// parseModels resolves a comma separated list of model names
+func (c *CompareCommand) parseModels(arg string) ([]string, error) {
+	var models []string
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		model := matchModel(c.catalog.Models(), name)
+		if model == "" {
+			return nil, fmt.Errorf("unknown model: %s", name)
+		}
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	if len(models) < 2 {
+		return nil, fmt.Errorf("at least two different models are needed to compare")
+	}
+	return models, nil
+}
+
+// printSummary shows the timing of every answer
+func (c *CompareCommand) printSummary(ctx *CommandContext, turn Turn) {
+	ctx.Config.InfoColor().Printf("\nComparison:\n\n")
+	fmt.Printf("  %-4s %-30s %10s %10s %8s %10s\n", "#", "Model", "Duration", "TTFC", "Chunks", "Length")
+	for i, cand := range turn.Candidates {
+		resp := cand.Response
+		line := fmt.Sprintf("  [%d]  %-30s %8dms %8dms %8d %10d", i+1, cand.Model, resp.DurationMs, resp.TTFCMs, resp.Chunks, len(resp.Output))
+		if cand.Status != db.StatusCompleted {
+			line += " (" + cand.Status + ")"
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// askSelection asks which answer to keep and returns its index
+func (c *CompareCommand) askSelection(n int) int {
+	input, err := ReadInputWithoutHistory(fmt.Sprintf("Keep which answer? [1-%d, Enter for 1]: ", n))
+	if err != nil || input == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(input)
+	if err != nil || i < 1 || i > n {
+		fmt.Println("Invalid selection, keeping answer 1")
+		return 0
+	}
+	return i - 1
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"tchat/internal/db"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// continuePrompt asks the model to resume a partial answer
+const continuePrompt = "Your previous answer was interrupted. Continue it exactly where it stopped, without repeating any of it."
+
+// ContinueCommand resumes an answer that was cancelled or failed
+type ContinueCommand struct {
+	store *db.Store
+}
+
+func NewContinueCommand(store *db.Store) *ContinueCommand {
+	return &ContinueCommand{
+		store: store,
+	}
+}
+
+func (c *ContinueCommand) Name() string {
+	return "continue"
+}
+
+func (c *ContinueCommand) Aliases() []string {
+	return []string{"cont"}
+}
+
+func (c *ContinueCommand) Description() string {
+	return "Continue the last answer if it was cancelled or failed"
+}
+
+func (c *ContinueCommand) Usage() string {
+	return "/continue"
+}
+
+func (c *ContinueCommand) Execute(ctx *CommandContext) ExecutionResult {
+	turn := ctx.LastTurn
+	if turn == nil || len(turn.Candidates) == 0 {
+		fmt.Println("Nothing to continue yet")
+		return REPLContinue
+	}
+	cand := &turn.Candidates[turn.Selected]
+	if cand.Status == "" || cand.Status == db.StatusCompleted {
+		fmt.Println("The last answer is complete, nothing to continue")
+		return REPLContinue
+	}
+	if cand.Response.Output == "" {
+		fmt.Println("The last answer is empty. Use /retry to generate it again")
+		return REPLContinue
+	}
+	if ctx.Generate == nil {
+		ctx.Config.ErrorColor().Println("Generation is not available")
+		return REPLContinue
+	}
+
+	// Replay the turn with the partial answer and ask the model to carry on
+	req := turn.Request
+	req.Model = cand.Model
+	req.ImagePaths = nil
+	req.History = append(append([]*ai.Message{}, turn.Request.History...),
+		ai.NewUserTextMessage(turn.Request.UserInput),
+		ai.NewModelTextMessage(cand.Response.Output),
+	)
+	req.UserInput = continuePrompt
+
+	ctx.Config.InfoColor().Printf("↻ Continuing answer with %s\n", req.Model)
+
+	resp, err := ctx.Generate(ctx.Ctx, req)
+
+	// A continuation that stops early is still kept, so /continue can be repeated
+	cand.Response.Output = joinContinuation(cand.Response.Output, resp.Output)
+	cand.Response.Reasoning = strings.TrimSpace(cand.Response.Reasoning + "\n\n" + resp.Reasoning)
+	cand.Response.DurationMs += resp.DurationMs
+	cand.Response.Chunks += resp.Chunks
+	cand.Response.ToolCalls = append(cand.Response.ToolCalls, resp.ToolCalls...)
+	cand.Status = StatusOf(err)
+	keepSelected(ctx)
+
+	if c.store != nil && turn.MsgId != 0 {
+		if err := c.store.UpdateTurnOutput(turn.MsgId, cand.Response.Output, cand.Response.Reasoning, cand.Status); err != nil {
+			slog.Error("Failed to update conversation in database", "error", err)
+		}
+	}
+
+	if err == nil {
+		ctx.Config.InfoColor().Printf("✓ Answer completed (%d characters)\n", len(cand.Response.Output))
+	}
+	return REPLContinue
+}
+
+// joinContinuation appends a continuation to a partial answer. Answers are
+// trimmed, so the space at the seam may be lost: one is added after a
+// sentence or clause that ends right at the seam, while a word cut in half
+// is joined as is.
+func joinContinuation(partial, continuation string) string {
+	if partial == "" || continuation == "" {
+		return partial + continuation
+	}
+	last, _ := utf8.DecodeLastRuneInString(partial)
+	first, _ := utf8.DecodeRuneInString(continuation)
+	if unicode.IsSpace(last) || unicode.IsSpace(first) {
+		return partial + continuation
+	}
+	if strings.ContainsRune(".,;:!?)", last) && unicode.IsLetter(first) {
+		return partial + " " + continuation
+	}
+	return partial + continuation
+}
@@ -0,0 +1,23 @@
+package command
+
+import "testing"
+
+func TestJoinContinuation(t *testing.T) {
+	tests := []struct {
+		partial, continuation, want string
+	}{
+		{"the qui", "ck fox", "the quick fox"},
+		{"the end.", "Next one", "the end. Next one"},
+		{"the end. ", "Next one", "the end. Next one"},
+		{"a list:", "\n- item", "a list:\n- item"},
+		{"done,", "then more", "done, then more"},
+		{"pi is 3.", "14", "pi is 3.14"},
+		{"", "start", "start"},
+		{"partial", "", "partial"},
+	}
+	for _, tt := range tests {
+		if got := joinContinuation(tt.partial, tt.continuation); got != tt.want {
+			t.Errorf("joinContinuation(%q, %q) = %q, want %q", tt.partial, tt.continuation, got, tt.want)
+		}
+	}
+}
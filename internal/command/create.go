@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tchat/internal/catalog"
+	"tchat/internal/ollama"
+)
+
+// CreateCommand builds a model from a base model with the current system
+// prompt and generation parameters and registers it
+type CreateCommand struct {
+	catalog *catalog.Catalog
+}
+
+func NewCreateCommand(catalog *catalog.Catalog) *CreateCommand {
+	return &CreateCommand{
+		catalog: catalog,
+	}
+}
+
+func (c *CreateCommand) Name() string {
+	return "create"
+}
+
+func (c *CreateCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *CreateCommand) Description() string {
+	return "Create a model with the current system prompt and parameters"
+}
+
+func (c *CreateCommand) Usage() string {
+	return "/create <name> [base model] - the base model defaults to the current one"
+}
+
+func (c *CreateCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if len(ctx.Args) == 0 || len(ctx.Args) > 2 {
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+
+	base := ctx.State.GetModel()
+	if len(ctx.Args) == 2 {
+		base = matchModel(c.catalog.Models(), ctx.Args[1])
+		if base == "" {
+			ctx.Config.ErrorColor().Printf("Unknown model: %s\n", ctx.Args[1])
+			return REPLContinue
+		}
+	}
+
+	// The model is created next to its base model
+	baseHost, _ := c.catalog.Resolve(base)
+	if !baseHost.IsOllama() {
+		ctx.Config.ErrorColor().Printf("%s is not served by Ollama, models can only be created from Ollama models\n", base)
+		return REPLContinue
+	}
+	host, name := c.catalog.Resolve(ctx.Args[0])
+	if host.Name != baseHost.Name {
+		if strings.HasPrefix(ctx.Args[0], host.Name+"/") {
+			ctx.Config.ErrorColor().Printf("The model must be created on %s, the host of %s\n", baseHost.Name, base)
+			return REPLContinue
+		}
+		host = baseHost
+	}
+	name = ollama.ModelName(name)
+	model := host.Name + "/" + name
+
+	_, from := ollama.SplitModel(base)
+	req := ollama.CreateRequest{
+		Model:      name,
+		From:       from,
+		System:     ctx.State.GetSystemPrompt(),
+		Parameters: ctx.State.Params(),
+	}
+
+	ctx.Config.InfoColor().Printf("\nModelfile for %s:\n\n", model)
+	fmt.Println(req.Modelfile())
+	if c.catalog.Contains(model) {
+		answer, err := ReadInputWithoutHistory(fmt.Sprintf("%s exists. Replace it? [y/N]: ", model))
+		if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			fmt.Println("Create cancelled")
+			return REPLContinue
+		}
+	}
+
+	createCtx, cancel := context.WithCancel(ctx.Ctx)
+	if ctx.Interruptible != nil {
+		createCtx, cancel = ctx.Interruptible(ctx.Ctx)
+	}
+	defer cancel()
+
+	ctx.Config.InfoColor().Printf("⚙ Creating %s from %s. Press Ctrl-C to cancel\n", model, base)
+	start := time.Now()
+	progress := &pullProgress{}
+	err := ollama.Create(createCtx, host.Address, req, progress.render)
+	progress.finish()
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("Create cancelled")
+			return REPLContinue
+		}
+		slog.Error("Failed to create model", "model", model, "from", base, "error", err)
+		ctx.Config.ErrorColor().Printf("Failed to create %s: %v\n", model, err)
+		return REPLContinue
+	}
+	slog.Info("Model created", "model", model, "from", base, "params", req.Parameters,
+		"duration_ms", time.Since(start).Milliseconds())
+
+	// Register with Genkit, so it can be selected right away
+	model, _ = c.catalog.Register(ctx.Ctx, model)
+	ctx.Config.InfoColor().Printf("✓ Created %s. Select it with /model\n", model)
+	return REPLContinue
+}
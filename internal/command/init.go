@@ -1,9 +1,13 @@
 package command
 
-import "tchat/internal/db"
+import (
+	"tchat/internal/catalog"
+	"tchat/internal/db"
+	"tchat/internal/prompts"
+)
 
 // InitializeRegistry creates and registers all available commands
-func InitializeRegistry(availableModels []string, store *db.Store) *Registry {
+func InitializeRegistry(catalog *catalog.Catalog, store *db.Store, promptLib *prompts.Library) *Registry {
 	registry := NewRegistry()
 
 	// Create help command with registry reference (will be set after other commands)
@@ -12,7 +16,7 @@ func InitializeRegistry(availableModels []string, store *db.Store) *Registry {
 	// Register all commands
 	registry.Register(NewQuitCommand())
 	registry.Register(NewSystemCommand())
-	registry.Register(NewModelCommand(availableModels))
+	registry.Register(NewModelCommand(catalog))
 	registry.Register(NewShowCommand())
 	registry.Register(NewConfigCommand())
 	registry.Register(NewClearCommand())
@@ -21,6 +25,19 @@ func InitializeRegistry(availableModels []string, store *db.Store) *Registry {
 	registry.Register(NewCopyCommand())
 	registry.Register(NewVersionCommand())
 	registry.Register(NewStatsCommand(store))
+	registry.Register(NewJSONCommand())
+	registry.Register(NewThinkCommand())
+	registry.Register(NewRetryCommand(store, catalog))
+	registry.Register(NewPickCommand(store))
+	registry.Register(NewContinueCommand(store))
+	registry.Register(NewCompareCommand(store, catalog))
+	registry.Register(NewPromptCommand(promptLib, store))
+	registry.Register(NewUnloadCommand(catalog))
+	registry.Register(NewPullCommand(catalog))
+	registry.Register(NewPsCommand(catalog))
+	registry.Register(NewParamCommand())
+	registry.Register(NewCreateCommand(catalog))
+	registry.Register(NewAgentCommand(store))
 	registry.Register(helpCmd)
 
 	return registry
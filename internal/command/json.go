@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+
+	"tchat/internal/flows"
+)
+
+// JSONCommand toggles structured JSON output mode
+type JSONCommand struct{}
+
+func NewJSONCommand() *JSONCommand {
+	return &JSONCommand{}
+}
+
+func (c *JSONCommand) Name() string {
+	return "json"
+}
+
+func (c *JSONCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *JSONCommand) Description() string {
+	return "Ask for validated JSON answers, optionally against a schema file"
+}
+
+func (c *JSONCommand) Usage() string {
+	return "/json [schema-file] to enable JSON mode, /json off to disable it"
+}
+
+func (c *JSONCommand) Execute(ctx *CommandContext) ExecutionResult {
+	enabled, schemaPath, _ := ctx.State.JSONMode()
+
+	if len(ctx.Args) == 0 {
+		if enabled {
+			c.showStatus(ctx, schemaPath)
+			return REPLContinue
+		}
+		ctx.State.EnableJSONMode("", nil)
+		ctx.Config.InfoColor().Println("✓ JSON mode enabled (no schema)")
+		fmt.Println("Answers must be valid JSON. Use /copy to copy the last object, /json off to disable")
+		return REPLContinue
+	}
+
+	if ctx.Args[0] == "off" {
+		ctx.State.DisableJSONMode()
+		ctx.Config.InfoColor().Println("✓ JSON mode disabled")
+		return REPLContinue
+	}
+
+	path := ctx.Args[0]
+	schema, err := flows.LoadJSONSchema(path)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("Failed to load schema: %v\n", err)
+		return REPLContinue
+	}
+
+	ctx.State.EnableJSONMode(path, schema)
+	ctx.Config.InfoColor().Printf("✓ JSON mode enabled with schema %s\n", path)
+	fmt.Println("Answers are validated and retried up to", flows.DefaultJSONAttempts, "times. Use /json off to disable")
+	return REPLContinue
+}
+
+// showStatus prints the current JSON mode settings
+func (c *JSONCommand) showStatus(ctx *CommandContext, schemaPath string) {
+	ctx.Config.InfoColor().Printf("\nJSON mode: ")
+	fmt.Println("enabled")
+	ctx.Config.InfoColor().Printf("Schema: ")
+	if schemaPath == "" {
+		fmt.Println("none")
+	} else {
+		fmt.Println(schemaPath)
+	}
+	fmt.Println("\nUse /json <schema-file> to change the schema or /json off to disable")
+	fmt.Println()
+}
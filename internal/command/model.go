@@ -1,19 +1,29 @@
 package command
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"tchat/internal/catalog"
+	"tchat/internal/ollama"
 )
 
+// modelLoadTimeout limits how long preloading or unloading a model may take
+const modelLoadTimeout = 5 * time.Minute
+
 // ModelCommand handles model switching
 type ModelCommand struct {
-	availableModels []string
+	catalog *catalog.Catalog
 }
 
-func NewModelCommand(models []string) *ModelCommand {
+func NewModelCommand(catalog *catalog.Catalog) *ModelCommand {
 	return &ModelCommand{
-		availableModels: models,
+		catalog: catalog,
 	}
 }
 
@@ -30,21 +40,43 @@ func (c *ModelCommand) Description() string {
 }
 
 func (c *ModelCommand) Usage() string {
-	return "/model - then select a model from the list"
+	return "/model - then select a model from the list, /model info [name] to show a model card, /model refresh to reload the list"
 }
 
 func (c *ModelCommand) Execute(ctx *CommandContext) ExecutionResult {
-	if len(c.availableModels) == 0 {
+	if len(ctx.Args) > 0 && ctx.Args[0] == "refresh" {
+		c.refresh(ctx)
+		return REPLContinue
+	}
+	if len(ctx.Args) > 0 && ctx.Args[0] == "info" {
+		name := ""
+		if len(ctx.Args) > 1 {
+			name = ctx.Args[1]
+		}
+		c.showInfo(ctx, name)
+		return REPLContinue
+	}
+
+	// With several hosts, check which are up before listing their models
+	if len(c.catalog.Hosts()) > 1 {
+		c.catalog.CheckHosts(ctx.Ctx)
+	}
+
+	models := c.catalog.Models()
+	if len(models) == 0 {
 		fmt.Println("No models available")
 		return REPLContinue
 	}
 
 	currentModel := ctx.State.GetModel()
 	fmt.Println("Current model:")
-	fmt.Printf("  %s\n", currentModel)
+	fmt.Printf("  %s\n\n", currentModel)
 
-	ctx.Config.InfoColor().Printf("\nAvailable Models:\n\n")
-	c.displayModels(ctx, currentModel)
+	// Show what is loaded, so switching can account for memory; /ps unloads
+	showRunning(ctx, c.catalog)
+
+	ctx.Config.InfoColor().Printf("Available Models:\n\n")
+	c.displayModels(ctx, models, currentModel)
 
 	// Read selection without adding to command history
 	selection, err := ReadInputWithoutHistory("Enter a number to select the model: ")
@@ -56,9 +88,13 @@ func (c *ModelCommand) Execute(ctx *CommandContext) ExecutionResult {
 		return REPLContinue
 	}
 
-	selectedModel := c.parseSelection(selection)
+	selectedModel := parseSelection(models, selection)
 	if selectedModel == "" {
-		fmt.Println("Invalid selection. Please enter a number between 1 and", len(c.availableModels))
+		if embedder := matchModel(c.catalog.Embedders(), selection); embedder != "" {
+			ctx.Config.ErrorColor().Printf("%s is an embedding model and cannot chat\n", embedder)
+			return REPLContinue
+		}
+		fmt.Println("Invalid selection. Please enter a number between 1 and", len(models))
 		return REPLContinue
 	}
 
@@ -66,32 +102,124 @@ func (c *ModelCommand) Execute(ctx *CommandContext) ExecutionResult {
 	return REPLContinue
 }
 
-// displayModels shows all available models with highlighting for the current one
-func (c *ModelCommand) displayModels(ctx *CommandContext, currentModel string) {
+// displayModels shows all available models with highlighting for the
+// current one, grouped by host if there are several
+func (c *ModelCommand) displayModels(ctx *CommandContext, models []string, currentModel string) {
+	hosts := c.catalog.Hosts()
+	multiHost := len(hosts) > 1
+	addresses := make(map[string]string, len(hosts))
+	for _, h := range hosts {
+		addresses[h.Name] = h.Address
+	}
+
 	highlightColor := ctx.Config.InfoColor()
-	for i, model := range c.availableModels {
+	lastHost := ""
+	for i, model := range models {
+		if host, _ := ollama.SplitModel(model); multiHost && host != lastHost {
+			fmt.Printf("  %s (%s)\n", host, addresses[host])
+			lastHost = host
+		}
+		marks := capabilityMarks(model)
 		if model == currentModel {
-			highlightColor.Printf("  [%d] %s (current)\n", i+1, model)
+			highlightColor.Printf("  [%d] %s%s (current)\n", i+1, model, marks)
 		} else {
-			fmt.Printf("  [%d] %s\n", i+1, model)
+			fmt.Printf("  [%d] %s%s\n", i+1, model, marks)
+		}
+	}
+	for _, model := range c.catalog.Removed() {
+		fmt.Printf("   -  %s (removed)\n", model)
+	}
+	if embedders := c.catalog.Embedders(); len(embedders) > 0 {
+		fmt.Printf("  Embedding models, not for chat: %s\n", strings.Join(embedders, ", "))
+	}
+	if multiHost {
+		for _, h := range hosts {
+			if !h.Healthy {
+				ctx.Config.ErrorColor().Printf("  %s (%s): unreachable\n", h.Name, h.Address)
+			}
 		}
 	}
 	fmt.Println()
 }
 
+// refresh reloads the model list from the Ollama hosts
+func (c *ModelCommand) refresh(ctx *CommandContext) {
+	changes, err := c.catalog.Refresh()
+	ReportModelChanges(ctx, changes)
+	ApplyModelChanges(ctx, c.catalog, changes)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("Failed to refresh models: %v\n", err)
+		return
+	}
+	if changes.Empty() {
+		ctx.Config.InfoColor().Printf("✓ Model list is up to date (%d models)\n", len(c.catalog.Models()))
+	}
+}
+
+// ReportModelChanges tells the user about models and hosts that changed in
+// a refresh. It only prints, so it may run while a generation is streaming.
+func ReportModelChanges(ctx *CommandContext, changes catalog.ModelChanges) {
+	for _, model := range changes.Added {
+		ctx.Config.InfoColor().Printf("+ New model: %s\n", model)
+	}
+	for _, model := range changes.Removed {
+		ctx.Config.ErrorColor().Printf("- Model removed: %s\n", model)
+	}
+	for _, host := range changes.HostsDown {
+		ctx.Config.ErrorColor().Printf("✗ Host %s is unreachable; its models are unavailable\n", host)
+	}
+	for _, host := range changes.HostsUp {
+		ctx.Config.InfoColor().Printf("✓ Host %s is reachable again\n", host)
+	}
+}
+
+// ApplyModelChanges switches to another model if a refresh made the current
+// one unavailable, preferring the configured fallback model. It changes the
+// model and clears the history, so call it between turns.
+func ApplyModelChanges(ctx *CommandContext, catalog *catalog.Catalog, changes catalog.ModelChanges) {
+	current := ctx.State.GetModel()
+	host, _ := ollama.SplitModel(current)
+	if !slices.Contains(changes.Removed, current) && !slices.Contains(changes.HostsDown, host) {
+		return
+	}
+	// The model may be back by the time the changes are applied
+	if catalog.Contains(current) {
+		return
+	}
+	next := fallbackModel(ctx, catalog)
+	if next == "" {
+		ctx.Config.ErrorColor().Printf("The current model %s is unavailable and no models are left. Pull one with /pull\n", current)
+		return
+	}
+	ctx.Config.ErrorColor().Printf("The current model %s is unavailable\n", current)
+	NewModelCommand(catalog).switchModel(ctx, next)
+}
+
+// fallbackModel returns the configured fallback model if it is available,
+// otherwise the first available model, empty if there is none
+func fallbackModel(ctx *CommandContext, catalog *catalog.Catalog) string {
+	if fallback := ctx.Config.FallbackModel(); fallback != "" && catalog.Contains(fallback) {
+		return fallback
+	}
+	if models := catalog.Models(); len(models) > 0 {
+		return models[0]
+	}
+	return ""
+}
+
 // parseSelection converts user input to a model name
 // Supports both numeric selection (1, 2, 3...) and direct model name
-func (c *ModelCommand) parseSelection(selection string) string {
+func parseSelection(models []string, selection string) string {
 	// Try parsing as number first
 	if num, err := strconv.Atoi(selection); err == nil {
-		if num >= 1 && num <= len(c.availableModels) {
-			return c.availableModels[num-1]
+		if num >= 1 && num <= len(models) {
+			return models[num-1]
 		}
 		return ""
 	}
 
 	// Check if it matches a model name directly
-	for _, model := range c.availableModels {
+	for _, model := range models {
 		if strings.EqualFold(model, selection) || strings.Contains(strings.ToLower(model), strings.ToLower(selection)) {
 			return model
 		}
@@ -107,6 +235,7 @@ func (c *ModelCommand) switchModel(ctx *CommandContext, newModel string) {
 		return
 	}
 
+	previousModel := ctx.State.GetModel()
 	ctx.State.SetModel(newModel)
 
 	// TBD: Shoudl I save config to persist changes like model selection or system prompt update?
@@ -115,4 +244,45 @@ func (c *ModelCommand) switchModel(ctx *CommandContext, newModel string) {
 	ctx.History.Clear()
 
 	ctx.Config.InfoColor().Printf("✓ Switched to %s (conversation history cleared for this model)\n", newModel)
+
+	// Free the memory of the previous model and load the new one in the
+	// background, so the first prompt does not pay the load time. Models of
+	// other providers are loaded by their server.
+	unload := ctx.Config.AutoUnload() && c.catalog.IsOllama(previousModel)
+	preload := c.catalog.IsOllama(newModel)
+	switch {
+	case unload && preload:
+		fmt.Printf("  Unloading %s and loading %s in the background\n", previousModel, newModel)
+	case unload:
+		fmt.Printf("  Unloading %s in the background\n", previousModel)
+	case preload:
+		fmt.Printf("  Loading %s in the background\n", newModel)
+	default:
+		return
+	}
+	go c.swapModels(previousModel, newModel, unload, preload)
+}
+
+// swapModels optionally unloads the previous model, then optionally preloads the new one
+func (c *ModelCommand) swapModels(previousModel, newModel string, unload, preload bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), modelLoadTimeout)
+	defer cancel()
+
+	if unload {
+		if err := ollama.Unload(ctx, c.catalog.AddressOf(previousModel), previousModel); err != nil {
+			slog.Warn("Failed to unload model", "model", previousModel, "error", err)
+		} else {
+			slog.Info("Model unloaded", "model", previousModel)
+		}
+	}
+
+	if !preload {
+		return
+	}
+	start := time.Now()
+	if err := ollama.Preload(ctx, c.catalog.AddressOf(newModel), newModel); err != nil {
+		slog.Warn("Failed to preload model", "model", newModel, "error", err)
+		return
+	}
+	slog.Info("Model preloaded", "model", newModel, "duration_ms", time.Since(start).Milliseconds())
 }
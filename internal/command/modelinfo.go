@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"tchat/internal/ollama"
+)
+
+// markedCapabilities are the capabilities shown in the model picker, in order
+var markedCapabilities = []string{"vision", "tools", "thinking", "embedding"}
+
+// maxLicenseLines limits how much of the license the model card shows
+const maxLicenseLines = 3
+
+// showInfo prints the model card of a model from /api/show
+func (c *ModelCommand) showInfo(ctx *CommandContext, name string) {
+	model := ctx.State.GetModel()
+	if name != "" {
+		model = matchModel(append(c.catalog.Models(), c.catalog.Embedders()...), name)
+		if model == "" {
+			ctx.Config.ErrorColor().Printf("Unknown model: %s\n", name)
+			return
+		}
+	}
+
+	if !c.catalog.IsOllama(model) {
+		ctx.Config.ErrorColor().Printf("Model cards are only available for Ollama models, %s is served by another provider\n", model)
+		return
+	}
+
+	_, modelName := ollama.SplitModel(model)
+	details, err := ollama.FetchModelDetals(ctx.Ctx, c.catalog.AddressOf(model), modelName)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("Failed to fetch details of %s: %v\n", model, err)
+		return
+	}
+
+	ctx.Config.InfoColor().Printf("\nModel card: %s\n\n", model)
+	printField("Family", strings.Join(details.Details.Families, ", "), details.Details.Family)
+	printField("Parent model", details.Details.ParentModel)
+	printField("Parameter size", details.Details.ParameterSize)
+	printField("Quantization", details.Details.QuantizationLevel)
+	printField("Format", details.Details.Format)
+	if n := details.ContextLength(); n > 0 {
+		printField("Context length", fmt.Sprintf("%d tokens", n))
+	}
+	printField("Capabilities", strings.Join(details.Capabilities, ", "))
+	if !details.ModifiedAt.IsZero() {
+		printField("Modified", details.ModifiedAt.Format("2006-01-02 15:04"))
+	}
+
+	if params := strings.TrimSpace(details.Parameters); params != "" {
+		fmt.Println("\n  Default parameters:")
+		for _, line := range strings.Split(params, "\n") {
+			fmt.Printf("    %s\n", strings.Join(strings.Fields(line), " "))
+		}
+	}
+
+	if license := strings.TrimSpace(details.License); license != "" {
+		fmt.Println("\n  License:")
+		lines := strings.Split(license, "\n")
+		for _, line := range lines[:min(len(lines), maxLicenseLines)] {
+			fmt.Printf("    %s\n", strings.TrimSpace(line))
+		}
+		if len(lines) > maxLicenseLines {
+			fmt.Printf("    ... (%d more lines)\n", len(lines)-maxLicenseLines)
+		}
+	}
+	fmt.Println()
+}
+
+// printField prints a model card field with the first non-empty value
+func printField(label string, values ...string) {
+	for _, v := range values {
+		if v != "" {
+			fmt.Printf("  %-16s %s\n", label+":", v)
+			return
+		}
+	}
+}
+
+// capabilityMarks lists the notable capabilities of a model for the picker
+func capabilityMarks(model string) string {
+	caps := ollama.Capabilities(model)
+	var marks []string
+	for _, c := range markedCapabilities {
+		if slices.Contains(caps, c) {
+			marks = append(marks, c)
+		}
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(marks, ", ") + "]"
+}
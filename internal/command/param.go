@@ -0,0 +1,116 @@
+package command
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// paramKind is the type of value a generation parameter takes
+type paramKind int
+
+const (
+	paramFloat paramKind = iota
+	paramInt
+	paramString
+)
+
+// params are the Ollama generation parameters that can be set with /param
+var params = map[string]paramKind{
+	"temperature":    paramFloat,
+	"top_p":          paramFloat,
+	"min_p":          paramFloat,
+	"repeat_penalty": paramFloat,
+	"top_k":          paramInt,
+	"num_ctx":        paramInt,
+	"num_predict":    paramInt,
+	"repeat_last_n":  paramInt,
+	"seed":           paramInt,
+	"stop":           paramString,
+}
+
+// ParamCommand sets generation parameters sent with every prompt
+type ParamCommand struct{}
+
+func NewParamCommand() *ParamCommand {
+	return &ParamCommand{}
+}
+
+func (c *ParamCommand) Name() string {
+	return "param"
+}
+
+func (c *ParamCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *ParamCommand) Description() string {
+	return "Set generation parameters such as temperature or num_ctx"
+}
+
+func (c *ParamCommand) Usage() string {
+	return "/param [name value] - e.g. /param temperature 0.2, /param num_ctx 8192, /param temperature off"
+}
+
+func (c *ParamCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if len(ctx.Args) == 0 {
+		c.show(ctx)
+		return REPLContinue
+	}
+	if len(ctx.Args) < 2 {
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+
+	name := strings.ToLower(ctx.Args[0])
+	kind, ok := params[name]
+	if !ok {
+		ctx.Config.ErrorColor().Printf("Unknown parameter: %s\n", name)
+		fmt.Println("Parameters:", strings.Join(slices.Sorted(maps.Keys(params)), ", "))
+		return REPLContinue
+	}
+
+	raw := strings.Join(ctx.Args[1:], " ")
+	if raw == "off" {
+		ctx.State.SetParam(name, nil)
+		ctx.Config.InfoColor().Printf("✓ %s uses the model default\n", name)
+		return REPLContinue
+	}
+	value, err := parseParam(kind, raw)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("Invalid value for %s: %v\n", name, err)
+		return REPLContinue
+	}
+	ctx.State.SetParam(name, value)
+	ctx.Config.InfoColor().Printf("✓ %s set to %v\n", name, value)
+	return REPLContinue
+}
+
+// show prints the parameters set for this session
+func (c *ParamCommand) show(ctx *CommandContext) {
+	set := ctx.State.Params()
+	ctx.Config.InfoColor().Printf("\nGeneration parameters:\n")
+	if len(set) == 0 {
+		fmt.Println("  (model defaults)")
+	}
+	for _, name := range slices.Sorted(maps.Keys(set)) {
+		fmt.Printf("  %-15s %v\n", name, set[name])
+	}
+	fmt.Println("\nParameters:", strings.Join(slices.Sorted(maps.Keys(params)), ", "))
+	fmt.Println()
+}
+
+// parseParam converts a parameter value of the given kind
+func parseParam(kind paramKind, raw string) (any, error) {
+	switch kind {
+	case paramFloat:
+		return strconv.ParseFloat(raw, 64)
+	case paramInt:
+		return strconv.Atoi(raw)
+	default:
+		// Ollama takes a list of stop sequences
+		return []string{raw}, nil
+	}
+}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tchat/internal/db"
+)
+
+// PickCommand chooses which candidate of the last turn is kept
+type PickCommand struct {
+	store *db.Store
+}
+
+func NewPickCommand(store *db.Store) *PickCommand {
+	return &PickCommand{
+		store: store,
+	}
+}
+
+func (c *PickCommand) Name() string {
+	return "pick"
+}
+
+func (c *PickCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *PickCommand) Description() string {
+	return "List candidates of the last answer or keep candidate N"
+}
+
+func (c *PickCommand) Usage() string {
+	return "/pick to list candidates, /pick N to keep candidate N"
+}
+
+func (c *PickCommand) Execute(ctx *CommandContext) ExecutionResult {
+	turn := ctx.LastTurn
+	if turn == nil || len(turn.Candidates) < 2 {
+		fmt.Println("No candidates to pick from. Use /retry to generate alternatives")
+		return REPLContinue
+	}
+
+	if len(ctx.Args) == 0 {
+		c.listCandidates(ctx)
+		return REPLContinue
+	}
+
+	n, err := strconv.Atoi(ctx.Args[0])
+	if err != nil || n < 1 || n > len(turn.Candidates) {
+		fmt.Println("Invalid selection. Please enter a number between 1 and", len(turn.Candidates))
+		return REPLContinue
+	}
+
+	selectCandidate(ctx, c.store, n-1)
+
+	fmt.Println()
+	ctx.Config.OutputColor().Println(turn.Candidates[n-1].Response.Output)
+	ctx.Config.InfoColor().Printf("\n✓ Keeping candidate %d (%s)\n", n, turn.Candidates[n-1].Model)
+	return REPLContinue
+}
+
+// listCandidates shows a one-line preview of every candidate
+func (c *PickCommand) listCandidates(ctx *CommandContext) {
+	turn := ctx.LastTurn
+	ctx.Config.InfoColor().Printf("\nCandidates:\n\n")
+	for i, cand := range turn.Candidates {
+		settings := cand.Model
+		if cand.Temperature != nil {
+			settings += fmt.Sprintf(", temperature %.2f", *cand.Temperature)
+		}
+		if cand.Status != "" && cand.Status != db.StatusCompleted {
+			settings += ", " + cand.Status
+		}
+		line := fmt.Sprintf("  [%d] %s (%s, %d ms)", i+1, preview(cand.Response.Output, 60), settings, cand.Response.DurationMs)
+		if i == turn.Selected {
+			ctx.Config.InfoColor().Printf("%s (current)\n", line)
+		} else {
+			fmt.Println(line)
+		}
+	}
+	fmt.Println()
+}
+
+// preview returns the first line of text shortened to n runes
+func preview(text string, n int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	runes := []rune(line)
+	if len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return line
+}
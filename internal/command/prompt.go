@@ -0,0 +1,136 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tchat/internal/db"
+	"tchat/internal/flows"
+	"tchat/internal/parser"
+	"tchat/internal/prompts"
+)
+
+// PromptCommand runs a dotprompt file from the prompts directory
+type PromptCommand struct {
+	library *prompts.Library
+	store   *db.Store
+}
+
+func NewPromptCommand(library *prompts.Library, store *db.Store) *PromptCommand {
+	return &PromptCommand{
+		library: library,
+		store:   store,
+	}
+}
+
+func (c *PromptCommand) Name() string {
+	return "prompt"
+}
+
+func (c *PromptCommand) Aliases() []string {
+	return []string{"p"}
+}
+
+func (c *PromptCommand) Description() string {
+	return "List prompt files or run one with variables"
+}
+
+func (c *PromptCommand) Usage() string {
+	return "/p to list prompts, /p <name> key=value ... to run a prompt"
+}
+
+func (c *PromptCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if c.library == nil {
+		ctx.Config.ErrorColor().Println("Prompts are not available")
+		return REPLContinue
+	}
+	if len(ctx.Args) == 0 {
+		c.listPrompts(ctx)
+		return REPLContinue
+	}
+	if ctx.Generate == nil {
+		ctx.Config.ErrorColor().Println("Generation is not available")
+		return REPLContinue
+	}
+
+	name := ctx.Args[0]
+	info, ok := c.library.Info(name)
+	if !ok {
+		ctx.Config.ErrorColor().Printf("Unknown prompt: %s\n", name)
+		fmt.Println("Use /p to list the prompts in", c.library.Dir())
+		return REPLContinue
+	}
+
+	input, err := c.library.Input(name, ctx.Args[1:])
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("%v\n", err)
+		if vars := info.Variables(); len(vars) > 0 {
+			fmt.Printf("Variables of %s: %s\n", name, strings.Join(vars, ", "))
+		}
+		return REPLContinue
+	}
+
+	req, err := c.library.Resolve(ctx.Ctx, flows.ChatRequest{
+		Model:        ctx.State.GetModel(),
+		SystemPrompt: ctx.State.GetSystemPrompt(),
+		History:      ctx.History.GetAll(),
+		Prompt:       name,
+		PromptInput:  input,
+	})
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("%v\n", err)
+		return REPLContinue
+	}
+
+	rendered := req.UserInput
+	var attachments []parser.Attachment
+	req.UserInput, attachments, ok = AttachFiles(ctx, rendered)
+	if !ok {
+		return REPLContinue
+	}
+
+	ctx.Config.InfoColor().Printf("📝 %s with %s\n", name, req.Model)
+
+	startTime := time.Now()
+	resp, err := ctx.Generate(ctx.Ctx, req)
+	if err != nil && resp.Output == "" {
+		return REPLContinue
+	}
+
+	// The rendered prompt becomes a regular turn, so /retry and /continue work on it
+	turn := NewTurn(0, req, resp, StatusOf(err))
+	turn.Input, turn.Attachments = rendered, attachments
+	turn.MsgId = saveTurn(c.store, ctx.SessionId, turn, startTime)
+	*ctx.LastTurn = turn
+	if err == nil || ctx.Config.KeepPartial() {
+		keepSelected(ctx)
+	}
+	return REPLContinue
+}
+
+// listPrompts shows the loaded prompts with their variables
+func (c *PromptCommand) listPrompts(ctx *CommandContext) {
+	names := c.library.Names()
+	if len(names) == 0 {
+		fmt.Printf("No prompts found. Add .prompt files to %s\n", c.library.Dir())
+		return
+	}
+
+	ctx.Config.InfoColor().Printf("\nPrompts in %s:\n\n", c.library.Dir())
+	for _, name := range names {
+		info, _ := c.library.Info(name)
+		line := "  " + name
+		if vars := info.Variables(); len(vars) > 0 {
+			line += " (" + strings.Join(vars, ", ") + ")"
+		}
+		fmt.Println(line)
+		if info.Description != "" {
+			fmt.Printf("      %s\n", info.Description)
+		}
+		if info.Model != "" {
+			fmt.Printf("      model: %s\n", info.Model)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tchat/internal/catalog"
+	"tchat/internal/ollama"
+	"tchat/internal/utils"
+)
+
+// psTimeout limits how long listing the running models may take
+const psTimeout = 10 * time.Second
+
+// PsCommand shows the models loaded in Ollama and unloads one on request
+type PsCommand struct {
+	catalog *catalog.Catalog
+}
+
+func NewPsCommand(catalog *catalog.Catalog) *PsCommand {
+	return &PsCommand{
+		catalog: catalog,
+	}
+}
+
+func (c *PsCommand) Name() string {
+	return "ps"
+}
+
+func (c *PsCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *PsCommand) Description() string {
+	return "Show loaded models and their memory use"
+}
+
+func (c *PsCommand) Usage() string {
+	return "/ps - then optionally select a model to unload"
+}
+
+func (c *PsCommand) Execute(ctx *CommandContext) ExecutionResult {
+	running, ok := showRunning(ctx, c.catalog)
+	if !ok || len(running) == 0 {
+		return REPLContinue
+	}
+
+	selection, err := ReadInputWithoutHistory("Enter a number to unload the model, or press Enter to keep all: ")
+	if err != nil {
+		return REPLExit
+	}
+	if selection == "" {
+		return REPLContinue
+	}
+	num, err := strconv.Atoi(selection)
+	if err != nil || num < 1 || num > len(running) {
+		fmt.Println("Invalid selection. Please enter a number between 1 and", len(running))
+		return REPLContinue
+	}
+
+	model := running[num-1].id
+	unloadCtx, cancel := context.WithTimeout(ctx.Ctx, modelLoadTimeout)
+	defer cancel()
+	if err := ollama.Unload(unloadCtx, c.catalog.AddressOf(model), model); err != nil {
+		ctx.Config.ErrorColor().Printf("Failed to unload %s: %v\n", model, err)
+		return REPLContinue
+	}
+	ctx.Config.InfoColor().Printf("✓ Unloaded %s\n", model)
+	return REPLContinue
+}
+
+// loadedModel is a model loaded on one of the hosts
+type loadedModel struct {
+	ollama.RunningModel
+	id string // model identifier with host prefix
+}
+
+// showRunning prints the models loaded on the reachable hosts as a
+// numbered table. Returns false if they could not be listed.
+func showRunning(ctx *CommandContext, catalog *catalog.Catalog) ([]loadedModel, bool) {
+	psCtx, cancel := context.WithTimeout(ctx.Ctx, psTimeout)
+	defer cancel()
+
+	hosts := catalog.Hosts()
+	var running []loadedModel
+	listed := false
+	for _, host := range hosts {
+		if !host.Healthy && len(hosts) > 1 || !host.IsOllama() {
+			continue
+		}
+		models, err := ollama.ListRunning(psCtx, host.Address)
+		if err != nil {
+			ctx.Config.ErrorColor().Printf("Failed to list loaded models of %s: %v\n", host.Name, err)
+			continue
+		}
+		listed = true
+		for _, m := range models {
+			running = append(running, loadedModel{RunningModel: m, id: host.Name + "/" + m.Name})
+		}
+	}
+	if !listed {
+		return nil, false
+	}
+
+	if len(running) == 0 {
+		fmt.Println("No models loaded")
+		return running, true
+	}
+
+	ctx.Config.InfoColor().Printf("Loaded Models:\n\n")
+	fmt.Printf("  %-4s %-30s %10s %-18s %8s  %s\n", "#", "Model", "Size", "Processor", "Context", "Unloads")
+	for i, m := range running {
+		name := m.Name
+		if len(hosts) > 1 {
+			name = m.id
+		}
+		fmt.Printf("  [%d]  %-30s %10s %-18s %8s  %s\n", i+1, name, utils.FormatBytes(m.Size),
+			processor(m.RunningModel), contextLength(m.ContextLength), expiry(m.ExpiresAt))
+	}
+	fmt.Println()
+	return running, true
+}
+
+// processor describes how a model is split between GPU and CPU memory
+func processor(m ollama.RunningModel) string {
+	if m.Size <= 0 {
+		return "-"
+	}
+	gpu := int(float64(m.SizeVRAM) / float64(m.Size) * 100)
+	switch {
+	case gpu >= 100:
+		return "100% GPU"
+	case gpu <= 0:
+		return "100% CPU"
+	default:
+		return fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+	}
+}
+
+// contextLength formats the context size of a loaded model
+func contextLength(n int) string {
+	if n <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
+
+// expiry describes when a model will be unloaded
+func expiry(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "-"
+	}
+	until := time.Until(expiresAt)
+	switch {
+	case until > 100*365*24*time.Hour: // keep_alive -1
+		return "never"
+	case until <= 0:
+		return "now"
+	default:
+		return "in " + until.Round(time.Second).String()
+	}
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tchat/internal/catalog"
+	"tchat/internal/ollama"
+	"tchat/internal/utils"
+)
+
+// pullBarWidth is the width of the download progress bar
+const pullBarWidth = 30
+
+// PullCommand downloads a model from the Ollama library and registers it
+type PullCommand struct {
+	catalog *catalog.Catalog
+}
+
+func NewPullCommand(catalog *catalog.Catalog) *PullCommand {
+	return &PullCommand{
+		catalog: catalog,
+	}
+}
+
+func (c *PullCommand) Name() string {
+	return "pull"
+}
+
+func (c *PullCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *PullCommand) Description() string {
+	return "Download a model from the Ollama library"
+}
+
+func (c *PullCommand) Usage() string {
+	return "/pull [host/]<model>, e.g. /pull llama3.2:3b or /pull workstation/llama3.1:8b"
+}
+
+func (c *PullCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if len(ctx.Args) != 1 {
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+	host, name := c.catalog.Resolve(ctx.Args[0])
+	if !host.IsOllama() {
+		ctx.Config.ErrorColor().Printf("%s is not an Ollama host, models can only be pulled to Ollama hosts\n", host.Name)
+		return REPLContinue
+	}
+	name = ollama.ModelName(name)
+
+	pullCtx, cancel := context.WithCancel(ctx.Ctx)
+	if ctx.Interruptible != nil {
+		pullCtx, cancel = ctx.Interruptible(ctx.Ctx)
+	}
+	defer cancel()
+
+	target := name
+	if len(c.catalog.Hosts()) > 1 {
+		target = host.Name + "/" + name
+	}
+	ctx.Config.InfoColor().Printf("⬇ Pulling %s. Press Ctrl-C to cancel\n", target)
+	start := time.Now()
+	progress := &pullProgress{}
+	err := ollama.Pull(pullCtx, host.Address, name, progress.render)
+	progress.finish()
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("Pull cancelled; run /pull again to resume the download")
+			return REPLContinue
+		}
+		slog.Error("Failed to pull model", "model", name, "error", err)
+		ctx.Config.ErrorColor().Printf("Failed to pull %s: %v\n", name, err)
+		return REPLContinue
+	}
+	slog.Info("Model pulled", "model", name, "duration_ms", time.Since(start).Milliseconds())
+
+	// Register with Genkit the same way as at startup, so it can be selected right away
+	model, embedding := c.catalog.Register(ctx.Ctx, host.Name+"/"+name)
+	if embedding {
+		ctx.Config.InfoColor().Printf("✓ Pulled %s in %s. It is an embedding model, available as embedder\n", model, time.Since(start).Round(time.Second))
+		return REPLContinue
+	}
+	ctx.Config.InfoColor().Printf("✓ Pulled %s in %s. Select it with /model\n", model, time.Since(start).Round(time.Second))
+	return REPLContinue
+}
+
+// pullProgress renders pull status updates, one line per status and a
+// progress bar for layer downloads
+type pullProgress struct {
+	status string
+	inLine bool // a status line without a trailing newline is shown
+}
+
+func (p *pullProgress) render(update ollama.PullProgress) {
+	if update.Status != p.status {
+		p.finish()
+		p.status = update.Status
+	}
+
+	line := "  " + shortDigest(update.Status)
+	if update.Total > 0 {
+		percent := float64(update.Completed) / float64(update.Total) * 100
+		line = fmt.Sprintf("%-26s %3.0f%% [%s] %s/%s", line, percent,
+			utils.ProgressBar(update.Completed, update.Total, pullBarWidth),
+			utils.FormatBytes(update.Completed), utils.FormatBytes(update.Total))
+	}
+	fmt.Printf("\r\033[K%s", line)
+	p.inLine = true
+}
+
+// finish ends the current status line
+func (p *pullProgress) finish() {
+	if p.inLine {
+		fmt.Println()
+		p.inLine = false
+	}
+}
+
+// shortDigest shortens "pulling sha256:<digest>" style statuses for display
+func shortDigest(status string) string {
+	const keep = 12
+	fields := strings.Fields(status)
+	for i, f := range fields {
+		f = strings.TrimPrefix(f, "sha256:")
+		if len(f) > keep+20 {
+			fields[i] = f[:keep]
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -28,18 +28,29 @@ func (r *Registry) Register(cmd Command) {
 	}
 }
 
-// Get retrieves a command by name or alias
-func (r *Registry) Get(name string) (Command, bool) {
+// Get retrieves a command by name or alias.
+// Arguments following the command name are ignored.
+func (r *Registry) Get(input string) (Command, bool) {
+	name, _ := Parse(input)
 	cmd, ok := r.commands[name]
 	return cmd, ok
 }
 
-// IsCommand checks if the input is a registered command
+// IsCommand checks if the input starts with a registered command
 func (r *Registry) IsCommand(input string) bool {
-	_, ok := r.commands[input]
+	_, ok := r.Get(input)
 	return ok
 }
 
+// Parse splits user input into the command name and its arguments
+func Parse(input string) (string, []string) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
 // AllCommands returns all unique commands (without duplicates from aliases)
 func (r *Registry) AllCommands() []Command {
 	seen := make(map[string]bool)
@@ -0,0 +1,152 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+
+	"tchat/internal/catalog"
+	"tchat/internal/db"
+	"tchat/internal/ollama"
+)
+
+// RetryCommand regenerates the last answer, optionally with another model or temperature
+type RetryCommand struct {
+	store   *db.Store
+	catalog *catalog.Catalog
+}
+
+func NewRetryCommand(store *db.Store, catalog *catalog.Catalog) *RetryCommand {
+	return &RetryCommand{
+		store:   store,
+		catalog: catalog,
+	}
+}
+
+func (c *RetryCommand) Name() string {
+	return "retry"
+}
+
+func (c *RetryCommand) Aliases() []string {
+	return []string{"regen"}
+}
+
+func (c *RetryCommand) Description() string {
+	return "Regenerate the last answer, optionally with another model or temperature"
+}
+
+func (c *RetryCommand) Usage() string {
+	return "/retry [model] [temperature] - e.g. /retry, /retry 0.9, /retry llama3.1 0.2"
+}
+
+func (c *RetryCommand) Execute(ctx *CommandContext) ExecutionResult {
+	turn := ctx.LastTurn
+	if turn == nil || len(turn.Candidates) == 0 {
+		fmt.Println("Nothing to retry yet")
+		return REPLContinue
+	}
+	if ctx.Generate == nil {
+		ctx.Config.ErrorColor().Println("Generation is not available")
+		return REPLContinue
+	}
+
+	model, temperature, err := c.parseArgs(ctx.Args)
+	if err != nil {
+		ctx.Config.ErrorColor().Printf("%v\n", err)
+		fmt.Println("Usage:", c.Usage())
+		return REPLContinue
+	}
+
+	req := turn.Request
+	if model != "" {
+		req.Model = model
+	}
+	if temperature != nil {
+		req.Options = maps.Clone(req.Options)
+		if req.Options == nil {
+			req.Options = make(map[string]any)
+		}
+		req.Options["temperature"] = *temperature
+	}
+
+	settings := req.Model
+	if t := temperatureOf(req); t != nil {
+		settings += fmt.Sprintf(", temperature %.2f", *t)
+	}
+	ctx.Config.InfoColor().Printf("↻ Regenerating with %s\n", settings)
+
+	resp, err := ctx.Generate(ctx.Ctx, req)
+	if err != nil {
+		return REPLContinue
+	}
+
+	// The first answer is stored as a candidate on the first retry
+	if len(turn.Candidates) == 1 {
+		c.saveCandidate(turn, 0)
+	}
+
+	turn.Candidates = append(turn.Candidates, Candidate{
+		Model:       req.Model,
+		Temperature: temperatureOf(req),
+		Response:    resp,
+		Status:      db.StatusCompleted,
+	})
+	newest := len(turn.Candidates) - 1
+	turn.Selected = newest
+	c.saveCandidate(turn, newest)
+	selectCandidate(ctx, c.store, newest)
+
+	ctx.Config.InfoColor().Printf("✓ Keeping candidate %d of %d. Use /pick N to choose another\n", newest+1, len(turn.Candidates))
+	return REPLContinue
+}
+
+// saveCandidate stores the candidate at index i if the turn was stored
+func (c *RetryCommand) saveCandidate(turn *Turn, i int) {
+	if c.store == nil || turn.MsgId == 0 {
+		return
+	}
+	if _, err := c.store.SaveCandidate(turn.toDBCandidate(i)); err != nil {
+		slog.Error("Failed to save candidate to database", "error", err)
+	}
+}
+
+// parseArgs reads an optional model and temperature in any order
+func (c *RetryCommand) parseArgs(args []string) (string, *float64, error) {
+	var model string
+	var temperature *float64
+
+	for _, arg := range args {
+		if t, err := strconv.ParseFloat(arg, 64); err == nil {
+			if t < 0 || t > 2 {
+				return "", nil, fmt.Errorf("temperature must be between 0 and 2")
+			}
+			temperature = &t
+			continue
+		}
+
+		model = matchModel(c.catalog.Models(), arg)
+		if model == "" {
+			return "", nil, fmt.Errorf("unknown model: %s", arg)
+		}
+	}
+
+	return model, temperature, nil
+}
+
+// matchModel finds an available model by exact or partial name, with or
+// without host prefix
+func matchModel(models []string, name string) string {
+	for _, model := range models {
+		if _, short := ollama.SplitModel(model); strings.EqualFold(model, name) || strings.EqualFold(short, name) {
+			return model
+		}
+	}
+	for _, model := range models {
+		if strings.Contains(strings.ToLower(model), strings.ToLower(name)) {
+			return model
+		}
+	}
+	return ""
+}
@@ -30,6 +30,14 @@ func (c *ShowCommand) Execute(ctx *CommandContext) ExecutionResult {
 	fmt.Printf("%s\n", ctx.State.GetSystemPrompt())
 	ctx.Config.InfoColor().Printf("Current model: ")
 	fmt.Printf("%s\n", ctx.State.GetModel())
+	if enabled, schemaPath, _ := ctx.State.JSONMode(); enabled {
+		ctx.Config.InfoColor().Printf("JSON mode: ")
+		if schemaPath == "" {
+			fmt.Println("enabled")
+		} else {
+			fmt.Printf("enabled (schema %s)\n", schemaPath)
+		}
+	}
 	fmt.Println()
 	return REPLContinue
 }
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"fmt"
+)
+
+// ThinkCommand controls how reasoning output of thinking models is displayed
+type ThinkCommand struct{}
+
+func NewThinkCommand() *ThinkCommand {
+	return &ThinkCommand{}
+}
+
+func (c *ThinkCommand) Name() string {
+	return "think"
+}
+
+func (c *ThinkCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *ThinkCommand) Description() string {
+	return "Show or collapse reasoning output of thinking models"
+}
+
+func (c *ThinkCommand) Usage() string {
+	return "/think on|off to show or collapse thinking, /think last to print the last thinking"
+}
+
+func (c *ThinkCommand) Execute(ctx *CommandContext) ExecutionResult {
+	if len(ctx.Args) == 0 {
+		state := "off (collapsed)"
+		if ctx.State.ShowThinking() {
+			state = "on"
+		}
+		ctx.Config.InfoColor().Printf("\nShow thinking: ")
+		fmt.Println(state)
+		fmt.Println("Use /think on|off to change, /think last to print the last thinking")
+		fmt.Println()
+		return REPLContinue
+	}
+
+	switch ctx.Args[0] {
+	case "on":
+		ctx.State.SetShowThinking(true)
+		ctx.Config.InfoColor().Println("✓ Thinking will be shown")
+	case "off":
+		ctx.State.SetShowThinking(false)
+		ctx.Config.InfoColor().Println("✓ Thinking will be collapsed")
+	case "last":
+		if ctx.LastReasoning == nil || *ctx.LastReasoning == "" {
+			fmt.Println("No thinking recorded for the last response")
+			return REPLContinue
+		}
+		fmt.Println()
+		ctx.Config.ThinkingColor().Println(*ctx.LastReasoning)
+		fmt.Println()
+	default:
+		fmt.Println("Usage:", c.Usage())
+	}
+
+	return REPLContinue
+}
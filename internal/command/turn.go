@@ -0,0 +1,200 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"tchat/internal/db"
+	"tchat/internal/flows"
+	"tchat/internal/parser"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// GenerateFunc runs a chat request through the REPL's generation path:
+// output is streamed to the terminal and Ctrl-C cancels it.
+// Errors are reported to the user before returning. A cancelled or failed
+// generation returns the partial answer with the error; cancellation is
+// reported as context.Canceled, or context.DeadlineExceeded when ctx timed out.
+type GenerateFunc func(ctx context.Context, req flows.ChatRequest) (flows.ChatResponse, error)
+
+// Turn records the last chat turn so it can be regenerated
+type Turn struct {
+	MsgId       int64               // Database ID of the turn, 0 if it was not stored
+	Request     flows.ChatRequest   // Request including the history before the turn and attached files
+	Input       string              // Input as typed, stored with the turn; Request.UserInput if empty
+	Attachments []parser.Attachment // Files attached with @path
+	Candidates  []Candidate
+	Selected    int  // Index of the candidate kept in history
+	InHistory   bool // Whether the selected answer is the last message in history
+}
+
+// Candidate is one generated answer for a turn
+type Candidate struct {
+	Model       string
+	Temperature *float64
+	Response    flows.ChatResponse
+	Status      string // db.StatusCompleted, db.StatusCancelled or db.StatusError
+}
+
+// NewTurn starts a turn record with its first answer
+func NewTurn(msgID int64, req flows.ChatRequest, resp flows.ChatResponse, status string) Turn {
+	return Turn{
+		MsgId:   msgID,
+		Request: req,
+		Candidates: []Candidate{{
+			Model:       req.Model,
+			Temperature: temperatureOf(req),
+			Response:    resp,
+			Status:      status,
+		}},
+	}
+}
+
+// StatusOf returns the turn status for the error returned by a GenerateFunc
+func StatusOf(err error) string {
+	switch {
+	case err == nil:
+		return db.StatusCompleted
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return db.StatusCancelled
+	default:
+		return db.StatusError
+	}
+}
+
+// toDBCandidate converts the candidate at index i to a database record
+func (t *Turn) toDBCandidate(i int) db.Candidate {
+	c := t.Candidates[i]
+	return db.Candidate{
+		MsgId:        t.MsgId,
+		CandidateNo:  i + 1,
+		ModelName:    c.Model,
+		Temperature:  c.Temperature,
+		ModelOutput:  c.Response.Output,
+		Reasoning:    c.Response.Reasoning,
+		Status:       c.Status,
+		DurationMs:   c.Response.DurationMs,
+		TTFCMs:       c.Response.TTFCMs,
+		Chunks:       c.Response.Chunks,
+		OutputLength: len(c.Response.Output),
+		Selected:     i == t.Selected,
+	}
+}
+
+// saveTurn stores the selected answer of a turn generated by a command and
+// returns its ID, 0 if it was not stored
+func saveTurn(store *db.Store, sessionID string, turn Turn, startTime time.Time) int64 {
+	if store == nil {
+		return 0
+	}
+
+	input := turn.Input
+	if input == "" {
+		input = turn.Request.UserInput
+	}
+	selected := turn.Candidates[turn.Selected]
+	resp := selected.Response
+	id, err := store.SaveTurn(db.ConversationTurn{
+		SessionId:    sessionID,
+		Timestamp:    startTime,
+		UserInput:    input,
+		ModelOutput:  resp.Output,
+		Reasoning:    resp.Reasoning,
+		Status:       selected.Status,
+		DurationMs:   resp.DurationMs,
+		TTFCMs:       resp.TTFCMs,
+		Chunks:       resp.Chunks,
+		InputLength:  len(input),
+		OutputLength: len(resp.Output),
+	})
+	if err != nil {
+		slog.Error("Failed to save conversation to database", "error", err)
+		return 0
+	}
+	if len(turn.Attachments) > 0 {
+		if err := store.SaveAttachments(id, ToDBAttachments(turn.Attachments)); err != nil {
+			slog.Error("Failed to save attachments to database", "error", err)
+		}
+	}
+	return id
+}
+
+// selectCandidate keeps the candidate at index i in history and as last response
+func selectCandidate(ctx *CommandContext, store *db.Store, i int) {
+	turn := ctx.LastTurn
+	turn.Selected = i
+	keepSelected(ctx)
+
+	if store != nil && turn.MsgId != 0 {
+		if err := store.SelectCandidate(turn.MsgId, i+1); err != nil {
+			slog.Error("Failed to select candidate in database", "error", err)
+		}
+	}
+}
+
+// keepSelected puts the selected answer of the last turn into history and
+// the last response. A turn that was left out of history, e.g. a partial
+// answer, is added to it.
+func keepSelected(ctx *CommandContext) {
+	turn := ctx.LastTurn
+	resp := turn.Candidates[turn.Selected].Response
+	msg := ai.NewModelTextMessage(resp.Output)
+
+	if turn.InHistory {
+		if !ctx.History.ReplaceLast(msg) {
+			slog.Warn("Last history message is not a model response, history not updated")
+		}
+	} else {
+		ctx.History.AddUserMessage(turn.Request.Model, turn.Request.UserInput)
+		ctx.History.Add(turn.Request.Model, msg)
+		turn.InHistory = true
+	}
+
+	if ctx.LastResponse != nil {
+		*ctx.LastResponse = resp.Output
+	}
+	if ctx.LastReasoning != nil {
+		*ctx.LastReasoning = resp.Reasoning
+	}
+}
+
+// ToDBToolCalls converts tool calls from the chat flow to database records
+func ToDBToolCalls(calls []flows.ToolCall) []db.ToolCall {
+	records := make([]db.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		input, _ := json.Marshal(call.Input)
+		output, _ := json.Marshal(call.Output)
+		records = append(records, db.ToolCall{
+			ToolName: call.Name,
+			Input:    string(input),
+			Output:   string(output),
+		})
+	}
+	return records
+}
+
+// ToDBAttachments converts files attached with @path to database records
+func ToDBAttachments(attachments []parser.Attachment) []db.Attachment {
+	records := make([]db.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		records = append(records, db.Attachment{
+			Path:      a.Path,
+			StartLine: a.StartLine,
+			EndLine:   a.EndLine,
+			Size:      len(a.Content),
+		})
+	}
+	return records
+}
+
+// temperatureOf returns the temperature option of a request, if set
+func temperatureOf(req flows.ChatRequest) *float64 {
+	if t, ok := req.Options["temperature"].(float64); ok {
+		return &t
+	}
+	return nil
+}
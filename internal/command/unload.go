@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"tchat/internal/catalog"
+	"tchat/internal/ollama"
+)
+
+// UnloadCommand frees the memory used by a model in Ollama
+type UnloadCommand struct {
+	catalog *catalog.Catalog
+}
+
+func NewUnloadCommand(catalog *catalog.Catalog) *UnloadCommand {
+	return &UnloadCommand{
+		catalog: catalog,
+	}
+}
+
+func (c *UnloadCommand) Name() string {
+	return "unload"
+}
+
+func (c *UnloadCommand) Aliases() []string {
+	return []string{}
+}
+
+func (c *UnloadCommand) Description() string {
+	return "Unload a model from memory (default: current model)"
+}
+
+func (c *UnloadCommand) Usage() string {
+	return "/unload [model]"
+}
+
+func (c *UnloadCommand) Execute(ctx *CommandContext) ExecutionResult {
+	model := ctx.State.GetModel()
+	if len(ctx.Args) > 0 {
+		model = matchModel(c.catalog.Models(), ctx.Args[0])
+		if model == "" {
+			ctx.Config.ErrorColor().Printf("Unknown model: %s\n", ctx.Args[0])
+			return REPLContinue
+		}
+	}
+
+	if !c.catalog.IsOllama(model) {
+		ctx.Config.ErrorColor().Printf("%s is not served by Ollama and cannot be unloaded\n", model)
+		return REPLContinue
+	}
+
+	unloadCtx, cancel := context.WithTimeout(ctx.Ctx, modelLoadTimeout)
+	defer cancel()
+	if err := ollama.Unload(unloadCtx, c.catalog.AddressOf(model), model); err != nil {
+		ctx.Config.ErrorColor().Printf("Failed to unload %s: %v\n", model, err)
+		return REPLContinue
+	}
+
+	ctx.Config.InfoColor().Printf("✓ Unloaded %s\n", model)
+	if model == ctx.State.GetModel() {
+		fmt.Println("  It is loaded again with the next prompt")
+	}
+	return REPLContinue
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"tchat/internal/catalog"
+)
+
+// Routing decisions for turns with images sent to a model without vision.
+// They are stored with the turn.
+const (
+	RouteVisionModel = "vision_model" // the turn was answered by the vision model
+	RouteSwitched    = "switched"     // the current model was switched to the vision model
+	RouteDropped     = "dropped"      // the images were left out
+)
+
+// ImageRoute is how a turn with images is answered
+type ImageRoute struct {
+	Model      string   // Model that answers the turn
+	ImagePaths []string // Images sent with the turn, nil if they were dropped
+	Decision   string   // One of the Route constants, empty if the model has vision
+}
+
+// String formats the decision as stored with the turn, e.g.
+// "vision_model:ollama/llava:7b" or "dropped"; empty if there was none
+func (r ImageRoute) String() string {
+	switch r.Decision {
+	case "", RouteDropped:
+		return r.Decision
+	default:
+		return r.Decision + ":" + r.Model
+	}
+}
+
+// RouteImages checks whether the current model is defined to accept images.
+// If it is not, the user chooses to send the turn to a vision model, to
+// switch to it, or to drop the images. Returns false if the user cancelled
+// the turn.
+func RouteImages(ctx *CommandContext, catalog *catalog.Catalog, imagePaths []string) (ImageRoute, bool) {
+	current := ctx.State.GetModel()
+	route := ImageRoute{Model: current, ImagePaths: imagePaths}
+	if len(imagePaths) == 0 || catalog.SupportsMedia(current) {
+		return route, true
+	}
+
+	vision := visionModel(ctx, catalog)
+	ctx.Config.ErrorColor().Printf("⚠ %s does not support images\n", current)
+	var choices []string
+	if vision != "" {
+		fmt.Printf("  [1] Send this turn to %s\n", vision)
+		fmt.Printf("  [2] Switch to %s\n", vision)
+		choices = append(choices, RouteVisionModel, RouteSwitched)
+	} else {
+		fmt.Println("  No model with vision is available. Pull one, e.g. /pull llava")
+	}
+	fmt.Printf("  [%d] Send without the images\n", len(choices)+1)
+	choices = append(choices, RouteDropped)
+
+	selection, err := ReadInputWithoutHistory("Enter a number, or press Enter to cancel: ")
+	decision := ""
+	if num, convErr := strconv.Atoi(selection); err == nil && convErr == nil && num >= 1 && num <= len(choices) {
+		decision = choices[num-1]
+	}
+
+	switch decision {
+	case RouteVisionModel:
+		route.Model = vision
+		ctx.Config.InfoColor().Printf("↪ Sending this turn to %s\n", vision)
+	case RouteSwitched:
+		NewModelCommand(catalog).switchModel(ctx, vision)
+		route.Model = ctx.State.GetModel()
+	case RouteDropped:
+		route.ImagePaths = nil
+		ctx.Config.InfoColor().Println("Sending without the images")
+	default:
+		fmt.Println("Message cancelled")
+		return route, false
+	}
+	route.Decision = decision
+	slog.Info("Images routed", "model", current, "decision", decision, "to", route.Model, "images", len(imagePaths))
+	return route, true
+}
+
+// visionModel returns the configured vision model if it is available and
+// accepts images, otherwise the first available model that does, empty if
+// there is none
+func visionModel(ctx *CommandContext, catalog *catalog.Catalog) string {
+	if model := ctx.Config.VisionModel(); model != "" && catalog.Contains(model) && catalog.SupportsMedia(model) {
+		return model
+	}
+	for _, model := range catalog.Models() {
+		if catalog.SupportsMedia(model) {
+			return model
+		}
+	}
+	return ""
+}
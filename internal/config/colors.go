@@ -24,6 +24,11 @@ func (c *Config) InfoColor() *color.Color {
 	return c.infoColor
 }
 
+// ThinkingColor returns the color for reasoning output (always dimmed)
+func (c *Config) ThinkingColor() *color.Color {
+	return c.thinkingColor
+}
+
 // AsciiArtColor returns the color for ASCII art (always yellow/bold)
 func (c *Config) AsciiArtColor() *color.Color {
 	return c.asciiArtColor
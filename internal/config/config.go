@@ -3,9 +3,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -20,7 +24,32 @@ type rawConfig struct {
 	Model        string `json:"Model"`
 
 	// History Settings
-	MaxMessages int `json:"max_messages"`
+	MaxMessages int   `json:"max_messages"`
+	KeepPartial *bool `json:"keep_partial"`
+
+	// Model Memory Settings
+	KeepAlive  map[string]string `json:"keep_alive"`
+	AutoUnload *bool             `json:"auto_unload"`
+
+	// Model list refresh, e.g. "1m"; off when empty
+	ModelRefreshInterval string `json:"model_refresh_interval"`
+
+	// Ollama hosts; OLLAMA_HOST is used when empty
+	Hosts         []HostConfig `json:"hosts"`
+	FallbackModel string       `json:"fallback_model"`
+
+	// Model that answers turns with images when the current model has no vision
+	VisionModel string `json:"vision_model"`
+
+	// HTTP client settings for all outbound calls
+	HTTP HTTPConfig `json:"http"`
+
+	// Agent Settings
+	AgentMaxSteps int    `json:"agent_max_steps"`
+	AgentTimeout  string `json:"agent_timeout"`
+
+	// Middlewares run around every generation, in order
+	Middleware []string `json:"middleware"`
 
 	// Logging Settings
 	LogLevel string `json:"log_level"`
@@ -34,8 +63,20 @@ type Config struct {
 	systemPrompt string
 	model        string
 	maxMessages  int
+	keepPartial  bool
+	keepAlive    map[string]string
+	autoUnload   bool
+	refreshEvery time.Duration
+	hosts        []HostConfig
+	fallback     string
+	visionModel  string
+	http         HTTPSettings
+	middleware   []string
 	logLevel     string
 
+	agentMaxSteps int
+	agentTimeout  time.Duration
+
 	colors ColorConfig
 
 	appDir string
@@ -44,9 +85,59 @@ type Config struct {
 	infoColor     *color.Color `json:"-"`
 	errorColor    *color.Color `json:"-"`
 	outputColor   *color.Color `json:"-"`
+	thinkingColor *color.Color `json:"-"`
 	asciiArtColor *color.Color `json:"-"`
 }
 
+// HostConfig is a named Ollama server, or a server with an OpenAI-compatible
+// API. Its models are listed with the name as prefix, e.g. "workstation/llama3.1:8b".
+type HostConfig struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Type   string `json:"type"`    // HostTypeOllama (default) or HostTypeOpenAI
+	APIKey string `json:"api_key"` // sent as bearer token to OpenAI-compatible servers
+}
+
+// Host types
+const (
+	HostTypeOllama = "ollama"
+	HostTypeOpenAI = "openai"
+)
+
+// HTTPConfig configures the HTTP client of all outbound calls, e.g. for an
+// Ollama server behind an authenticating reverse proxy with an internal CA
+type HTTPConfig struct {
+	Timeout        string            `json:"timeout"`         // requests that are not streamed, e.g. "30s"
+	ConnectTimeout string            `json:"connect_timeout"` // connecting, including the TLS handshake
+	Headers        map[string]string `json:"headers"`         // sent to the configured hosts only
+	CAFile         string            `json:"ca_file"`         // PEM bundle trusted in addition to the system roots
+	Proxy          string            `json:"proxy"`           // proxy URL; HTTP_PROXY and HTTPS_PROXY otherwise
+}
+
+// HTTPSettings are the parsed HTTP client settings
+type HTTPSettings struct {
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	Headers        map[string]string
+	CAFile         string
+	Proxy          string
+}
+
+// String lists the header names only, since their values may be secrets
+func (s HTTPSettings) String() string {
+	names := slices.Sorted(maps.Keys(s.Headers))
+	return fmt.Sprintf("timeout %s, connect timeout %s, headers %v, CA file %q, proxy %q",
+		s.Timeout, s.ConnectTimeout, names, s.CAFile, s.Proxy)
+}
+
+// String leaves out the API key
+func (h HostConfig) String() string {
+	if h.Type == "" {
+		return fmt.Sprintf("{%s %s}", h.Name, h.URL)
+	}
+	return fmt.Sprintf("{%s %s %s}", h.Name, h.URL, h.Type)
+}
+
 // colorConfig holds color configuration for UI elements
 type ColorConfig struct {
 	Prompt string `json:"prompt"` // Prompt color (>)
@@ -91,6 +182,12 @@ func New() (*Config, error) {
 func (c *Config) setDefaults() {
 	c.systemPrompt = DefaultSystemPrompt
 	c.maxMessages = DefaultMaxMessages
+	c.keepPartial = DefaultKeepPartial
+	c.autoUnload = DefaultAutoUnload
+	c.agentMaxSteps = DefaultAgentMaxSteps
+	c.agentTimeout = DefaultAgentTimeout
+	c.http.Timeout = DefaultHTTPTimeout
+	c.http.ConnectTimeout = DefaultHTTPConnectTimeout
 	c.logLevel = DefaultLogLevel
 
 	// Set default colors
@@ -126,6 +223,69 @@ func (c *Config) GetMaxMessages() int {
 	return c.maxMessages
 }
 
+// KeepPartial reports whether cancelled or failed answers are kept in the
+// conversation history
+func (c *Config) KeepPartial() bool {
+	return c.keepPartial
+}
+
+// KeepAlive returns how long Ollama keeps each model loaded, keyed by model
+// name. The "default" key applies to models without their own setting.
+func (c *Config) KeepAlive() map[string]string {
+	return maps.Clone(c.keepAlive)
+}
+
+// AutoUnload reports whether the previous model is unloaded when switching models
+func (c *Config) AutoUnload() bool {
+	return c.autoUnload
+}
+
+// ModelRefreshInterval returns how often the model list is refreshed in the
+// background, 0 when polling is off
+func (c *Config) ModelRefreshInterval() time.Duration {
+	return c.refreshEvery
+}
+
+// Hosts returns the configured Ollama hosts, empty to use OLLAMA_HOST
+func (c *Config) Hosts() []HostConfig {
+	return slices.Clone(c.hosts)
+}
+
+// FallbackModel returns the model to fail over to when the host of the
+// current model is unreachable, empty for none
+func (c *Config) FallbackModel() string {
+	return c.fallback
+}
+
+// VisionModel returns the model offered for turns with images when the
+// current model has no vision, empty to offer the first one available
+func (c *Config) VisionModel() string {
+	return c.visionModel
+}
+
+// HTTP returns the settings of the HTTP client used for all outbound calls
+func (c *Config) HTTP() HTTPSettings {
+	s := c.http
+	s.Headers = maps.Clone(c.http.Headers)
+	return s
+}
+
+// AgentMaxSteps returns the maximum number of steps of an /agent run
+func (c *Config) AgentMaxSteps() int {
+	return c.agentMaxSteps
+}
+
+// AgentTimeout returns the wall-clock budget of an /agent run
+func (c *Config) AgentTimeout() time.Duration {
+	return c.agentTimeout
+}
+
+// Middleware returns the names of the middlewares to run around each
+// generation, in order
+func (c *Config) Middleware() []string {
+	return slices.Clone(c.middleware)
+}
+
 func (c *Config) GetLogLevel() string {
 	return c.logLevel
 }
@@ -144,11 +304,32 @@ func (c *Config) String() string {
   Model: %s,
   System Prompt: %s
   Max Messages: %d
+  Keep Partial Answers: %t
+  Keep Alive: %v
+  Auto Unload: %t
+  Model Refresh Interval: %s
+  Hosts: %v
+  Fallback Model: %s
+  Vision Model: %s
+  HTTP: %s
+  Agent Budget: %d steps, %s
+  Middleware: %v
   Log Level: %s
   Config File: %s`,
 		c.model,
 		c.systemPrompt,
 		c.maxMessages,
+		c.keepPartial,
+		c.keepAlive,
+		c.autoUnload,
+		c.refreshEvery,
+		c.hosts,
+		c.fallback,
+		c.visionModel,
+		c.http,
+		c.agentMaxSteps,
+		c.agentTimeout,
+		c.middleware,
 		c.logLevel,
 		c.ConfigPath(),
 	)
@@ -186,6 +367,7 @@ func (c *Config) initializeColors() {
 	c.infoColor = color.New(parseColorName(c.colors.Info))
 	c.errorColor = color.New(parseColorName(c.colors.Error))
 	c.outputColor = color.New(parseColorName(c.colors.Output))
+	c.thinkingColor = color.New(color.Faint)                // Always dimmed
 	c.asciiArtColor = color.New(color.FgYellow, color.Bold) // Always yellow/bold
 }
 
@@ -232,6 +414,47 @@ func (c *Config) load() error {
 	if r.MaxMessages != 0 {
 		c.maxMessages = r.MaxMessages
 	}
+	if r.KeepPartial != nil {
+		c.keepPartial = *r.KeepPartial
+	}
+	if r.KeepAlive != nil {
+		c.keepAlive = r.KeepAlive
+	}
+	if r.AutoUnload != nil {
+		c.autoUnload = *r.AutoUnload
+	}
+	if r.ModelRefreshInterval != "" {
+		interval, err := time.ParseDuration(r.ModelRefreshInterval)
+		if err != nil {
+			return fmt.Errorf("invalid model_refresh_interval: %w", err)
+		}
+		if interval < 0 {
+			return fmt.Errorf("invalid model_refresh_interval: must not be negative")
+		}
+		c.refreshEvery = interval
+	}
+	if err := validateHosts(r.Hosts); err != nil {
+		return err
+	}
+	c.hosts = r.Hosts
+	c.fallback = r.FallbackModel
+	c.visionModel = r.VisionModel
+	if err := c.loadHTTP(r.HTTP); err != nil {
+		return err
+	}
+	if r.AgentMaxSteps > 0 {
+		c.agentMaxSteps = r.AgentMaxSteps
+	}
+	if r.AgentTimeout != "" {
+		timeout, err := time.ParseDuration(r.AgentTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid agent_timeout: %w", err)
+		}
+		c.agentTimeout = timeout
+	}
+	if r.Middleware != nil {
+		c.middleware = r.Middleware
+	}
 	if r.LogLevel != "" {
 		c.logLevel = r.LogLevel
 	}
@@ -239,3 +462,45 @@ func (c *Config) load() error {
 
 	return nil
 }
+
+// loadHTTP parses the HTTP client settings
+func (c *Config) loadHTTP(r HTTPConfig) error {
+	if r.Timeout != "" {
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid http.timeout: %w", err)
+		}
+		c.http.Timeout = timeout
+	}
+	if r.ConnectTimeout != "" {
+		timeout, err := time.ParseDuration(r.ConnectTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid http.connect_timeout: %w", err)
+		}
+		c.http.ConnectTimeout = timeout
+	}
+	c.http.Headers = r.Headers
+	c.http.CAFile = r.CAFile
+	c.http.Proxy = r.Proxy
+	return nil
+}
+
+// validateHosts checks that every host has a URL and a unique name that
+// can be used as model prefix
+func validateHosts(hosts []HostConfig) error {
+	seen := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		switch {
+		case h.Name == "" || strings.Contains(h.Name, "/"):
+			return fmt.Errorf("invalid host name %q: it must not be empty or contain /", h.Name)
+		case h.URL == "":
+			return fmt.Errorf("host %s has no url", h.Name)
+		case seen[h.Name]:
+			return fmt.Errorf("duplicate host name %s", h.Name)
+		case h.Type != "" && h.Type != HostTypeOllama && h.Type != HostTypeOpenAI:
+			return fmt.Errorf("host %s has unknown type %q, use %q or %q", h.Name, h.Type, HostTypeOllama, HostTypeOpenAI)
+		}
+		seen[h.Name] = true
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	// DefaultSystemPrompt is the default system prompt for the AI
 	DefaultSystemPrompt = "You are a helpful assistant"
@@ -10,6 +12,28 @@ const (
 	// DefaultMaxMessages is the default maximum number of messages to keep in history
 	DefaultMaxMessages = 5
 
+	// DefaultKeepPartial keeps cancelled or failed answers in the conversation history
+	DefaultKeepPartial = true
+
+	// DefaultAutoUnload unloads the previous model from Ollama when switching models
+	DefaultAutoUnload = true
+
+	// DefaultAgentMaxSteps is the maximum number of steps of an /agent run
+	DefaultAgentMaxSteps = 10
+
+	// DefaultAgentTimeout is the wall-clock budget of an /agent run
+	DefaultAgentTimeout = 5 * time.Minute
+
+	// DefaultHTTPTimeout limits HTTP requests whose response is not streamed,
+	// such as listing models or downloading images
+	DefaultHTTPTimeout = 30 * time.Second
+
+	// DefaultHTTPConnectTimeout limits connecting, including the TLS handshake
+	DefaultHTTPConnectTimeout = 10 * time.Second
+
+	// DefaultShowThinking shows the reasoning of thinking models while streaming
+	DefaultShowThinking = true
+
 	// DefaultLogLevel is the default logging level
 	DefaultLogLevel = "info"
 
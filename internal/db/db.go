@@ -17,6 +17,9 @@ type ConversationTurn struct {
 	MsgId        int64
 	UserInput    string
 	ModelOutput  string
+	Reasoning    string
+	Status       string // StatusCompleted, StatusCancelled or StatusError
+	Routing      string // How a turn with images for a model without vision was answered, e.g. "dropped"
 	DurationMs   int64
 	TTFCMs       int64
 	Chunks       int
@@ -25,6 +28,50 @@ type ConversationTurn struct {
 	Timestamp    time.Time
 }
 
+// Status values of a conversation turn
+const (
+	StatusCompleted = "completed" // The model finished the answer
+	StatusCancelled = "cancelled" // The user cancelled the generation, the answer is partial
+	StatusError     = "error"     // The generation failed, the answer is partial or empty
+)
+
+// ToolCall represents a tool invocation made while generating a turn
+type ToolCall struct {
+	MsgId     int64
+	ToolName  string
+	Input     string // JSON encoded tool input
+	Output    string // JSON encoded tool output
+	CreatedAt time.Time
+}
+
+// Attachment records a file attached to a conversation turn with @path
+type Attachment struct {
+	MsgId     int64
+	Path      string
+	StartLine int // first attached line; 0 when the whole file was attached
+	EndLine   int
+	Size      int // bytes attached
+	CreatedAt time.Time
+}
+
+// Candidate represents one of several generated answers for a conversation turn.
+// The selected candidate is also stored in chat_messages.
+type Candidate struct {
+	MsgId        int64
+	CandidateNo  int
+	ModelName    string
+	Temperature  *float64
+	ModelOutput  string
+	Reasoning    string
+	Status       string
+	DurationMs   int64
+	TTFCMs       int64
+	Chunks       int
+	OutputLength int
+	Selected     bool
+	CreatedAt    time.Time
+}
+
 // Session represents a chat session record.
 type Session struct {
 	SessionId string
@@ -77,6 +124,9 @@ CREATE TABLE IF NOT EXISTS chat_messages (
 		session_id TEXT NOT NULL,
 		user_input TEXT NOT NULL,
 		llm_response TEXT NOT NULL,
+		reasoning TEXT,
+		status TEXT NOT NULL DEFAULT 'completed',
+		routing TEXT,
 		duration_ms INTEGER NOT NULL,
 		ttfc_ms INTEGER,
 		chunks INTEGER,
@@ -87,6 +137,46 @@ CREATE TABLE IF NOT EXISTS chat_messages (
 	);	
 
 
+	CREATE TABLE IF NOT EXISTS tool_calls (
+		id 			INTEGER PRIMARY KEY AUTOINCREMENT,
+		msg_id 		INTEGER NOT NULL,
+		tool_name 	TEXT NOT NULL,
+		input 		TEXT,
+		output 		TEXT,
+		created_at 	DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(msg_id) REFERENCES chat_messages(msg_id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS attachments (
+		id 			INTEGER PRIMARY KEY AUTOINCREMENT,
+		msg_id 		INTEGER NOT NULL,
+		path 		TEXT NOT NULL,
+		start_line 	INTEGER,
+		end_line 	INTEGER,
+		size 		INTEGER NOT NULL,
+		created_at 	DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(msg_id) REFERENCES chat_messages(msg_id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS chat_candidates (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		msg_id 			INTEGER NOT NULL,
+		candidate_no 	INTEGER NOT NULL,
+		model_name 		TEXT NOT NULL,
+		temperature 	REAL,
+		llm_response 	TEXT NOT NULL,
+		reasoning 		TEXT,
+		status 			TEXT NOT NULL DEFAULT 'completed',
+		duration_ms 	INTEGER NOT NULL,
+		ttfc_ms 		INTEGER,
+		chunks 			INTEGER,
+		output_length 	INTEGER,
+		selected 		INTEGER NOT NULL DEFAULT 0,
+		created_at 		DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(msg_id, candidate_no),
+		FOREIGN KEY(msg_id) REFERENCES chat_messages(msg_id) ON DELETE CASCADE
+	);
+
 	CREATE TABLE IF NOT EXISTS chat_history (
 		id 			INTEGER PRIMARY KEY AUTOINCREMENT,
 		role 		TEXT NOT NULL,
@@ -98,9 +188,62 @@ CREATE TABLE IF NOT EXISTS chat_messages (
 		return err
 	}
 
+	return s.migrate()
+}
+
+// columnMigrations lists columns added after the initial schema.
+// They are added to existing databases on startup.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"chat_messages", "reasoning", "TEXT"},
+	{"chat_messages", "status", "TEXT NOT NULL DEFAULT 'completed'"},
+	{"chat_candidates", "status", "TEXT NOT NULL DEFAULT 'completed'"},
+	{"chat_messages", "routing", "TEXT"},
+}
+
+// migrate adds missing columns to tables created by older versions
+func (s *Store) migrate() error {
+	for _, m := range columnMigrations {
+		exists, err := s.hasColumn(m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
+		}
+	}
 	return nil
 }
 
+// hasColumn reports whether a table has the given column
+func (s *Store) hasColumn(table, column string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read table info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 // CreateSession inserts a new chat session.
 func (s *Store) CreateSession(session Session) error {
 	query := `
@@ -181,14 +324,17 @@ func (s *Store) ListSessions(limit int) ([]Session, error) {
 func (s *Store) SaveTurn(turn ConversationTurn) (int64, error) {
 	query := `
 		INSERT INTO chat_messages (
-			session_id, user_input, llm_response, duration_ms, ttfc_ms, chunks, input_length, output_length
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			session_id, user_input, llm_response, reasoning, status, routing, duration_ms, ttfc_ms, chunks, input_length, output_length
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := s.db.Exec(query,
 		turn.SessionId,
 		turn.UserInput,
 		turn.ModelOutput,
+		turn.Reasoning,
+		statusOrCompleted(turn.Status),
+		turn.Routing,
 		turn.DurationMs,
 		turn.TTFCMs,
 		turn.Chunks,
@@ -207,10 +353,323 @@ func (s *Store) SaveTurn(turn ConversationTurn) (int64, error) {
 	return id, nil
 }
 
+// UpdateTurnOutput replaces the answer of a stored turn and of its selected
+// candidate, e.g. when a partial answer was continued
+func (s *Store) UpdateTurnOutput(msgID int64, output, reasoning, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	status = statusOrCompleted(status)
+	result, err := tx.Exec(`
+		UPDATE chat_messages
+		SET llm_response = ?, reasoning = ?, status = ?, output_length = ?
+		WHERE msg_id = ?
+	`, output, reasoning, status, len(output), msgID)
+	if err != nil {
+		return fmt.Errorf("failed to update chat message: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("chat message not found")
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE chat_candidates
+		SET llm_response = ?, reasoning = ?, status = ?, output_length = ?
+		WHERE msg_id = ? AND selected = 1
+	`, output, reasoning, status, len(output), msgID); err != nil {
+		return fmt.Errorf("failed to update candidate: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// statusOrCompleted defaults an empty status to StatusCompleted
+func statusOrCompleted(status string) string {
+	if status == "" {
+		return StatusCompleted
+	}
+	return status
+}
+
+// SaveToolCalls saves the tool calls made for a conversation turn
+func (s *Store) SaveToolCalls(msgID int64, calls []ToolCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO tool_calls (msg_id, tool_name, input, output)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, call := range calls {
+		if _, err := stmt.Exec(msgID, call.ToolName, call.Input, call.Output); err != nil {
+			return fmt.Errorf("failed to insert tool call: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetToolCalls returns the tool calls recorded for a message, in call order
+func (s *Store) GetToolCalls(msgID int64) ([]ToolCall, error) {
+	query := `
+		SELECT msg_id, tool_name, input, output, created_at
+		FROM tool_calls
+		WHERE msg_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := s.db.Query(query, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tool calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []ToolCall
+	for rows.Next() {
+		var call ToolCall
+		var input, output sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&call.MsgId, &call.ToolName, &input, &output, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tool call: %w", err)
+		}
+		call.Input = input.String
+		call.Output = output.String
+		if createdAt.Valid {
+			call.CreatedAt = createdAt.Time
+		}
+		calls = append(calls, call)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tool calls: %w", err)
+	}
+
+	return calls, nil
+}
+
+// SaveAttachments saves the files attached to a conversation turn
+func (s *Store) SaveAttachments(msgID int64, attachments []Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO attachments (msg_id, path, start_line, end_line, size)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, a := range attachments {
+		if _, err := stmt.Exec(msgID, a.Path, a.StartLine, a.EndLine, a.Size); err != nil {
+			return fmt.Errorf("failed to insert attachment: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAttachments returns the files attached to a message, in prompt order
+func (s *Store) GetAttachments(msgID int64) ([]Attachment, error) {
+	query := `
+		SELECT msg_id, path, start_line, end_line, size, created_at
+		FROM attachments
+		WHERE msg_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := s.db.Query(query, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		var startLine, endLine sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&a.MsgId, &a.Path, &startLine, &endLine, &a.Size, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		a.StartLine = int(startLine.Int64)
+		a.EndLine = int(endLine.Int64)
+		if createdAt.Valid {
+			a.CreatedAt = createdAt.Time
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// SaveCandidate saves a generated answer for a conversation turn
+func (s *Store) SaveCandidate(c Candidate) (int64, error) {
+	query := `
+		INSERT INTO chat_candidates (
+			msg_id, candidate_no, model_name, temperature, llm_response, reasoning, status,
+			duration_ms, ttfc_ms, chunks, output_length, selected
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var temperature sql.NullFloat64
+	if c.Temperature != nil {
+		temperature = sql.NullFloat64{Float64: *c.Temperature, Valid: true}
+	}
+
+	result, err := s.db.Exec(query,
+		c.MsgId,
+		c.CandidateNo,
+		c.ModelName,
+		temperature,
+		c.ModelOutput,
+		c.Reasoning,
+		statusOrCompleted(c.Status),
+		c.DurationMs,
+		c.TTFCMs,
+		c.Chunks,
+		c.OutputLength,
+		c.Selected,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert candidate: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// SelectCandidate marks a candidate as selected and copies its answer to the conversation turn
+func (s *Store) SelectCandidate(msgID int64, candidateNo int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE chat_candidates SET selected = (candidate_no = ?) WHERE msg_id = ?`, candidateNo, msgID); err != nil {
+		return fmt.Errorf("failed to select candidate: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE chat_messages SET
+			llm_response = c.llm_response,
+			reasoning = c.reasoning,
+			status = c.status,
+			duration_ms = c.duration_ms,
+			ttfc_ms = c.ttfc_ms,
+			chunks = c.chunks,
+			output_length = c.output_length
+		FROM (SELECT * FROM chat_candidates WHERE msg_id = ? AND candidate_no = ?) AS c
+		WHERE chat_messages.msg_id = c.msg_id
+	`, msgID, candidateNo)
+	if err != nil {
+		return fmt.Errorf("failed to update chat message: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("candidate not found")
+	}
+
+	return tx.Commit()
+}
+
+// GetCandidates returns all candidates of a conversation turn ordered by candidate number
+func (s *Store) GetCandidates(msgID int64) ([]Candidate, error) {
+	query := `
+		SELECT msg_id, candidate_no, model_name, temperature, llm_response, reasoning, status,
+		       duration_ms, ttfc_ms, chunks, output_length, selected, created_at
+		FROM chat_candidates
+		WHERE msg_id = ?
+		ORDER BY candidate_no ASC
+	`
+
+	rows, err := s.db.Query(query, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var c Candidate
+		var temperature sql.NullFloat64
+		var reasoning sql.NullString
+		var ttfcMs, chunks, outputLength sql.NullInt64
+		var createdAt sql.NullTime
+
+		if err := rows.Scan(
+			&c.MsgId,
+			&c.CandidateNo,
+			&c.ModelName,
+			&temperature,
+			&c.ModelOutput,
+			&reasoning,
+			&c.Status,
+			&c.DurationMs,
+			&ttfcMs,
+			&chunks,
+			&outputLength,
+			&c.Selected,
+			&createdAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan candidate: %w", err)
+		}
+
+		if temperature.Valid {
+			c.Temperature = &temperature.Float64
+		}
+		c.Reasoning = reasoning.String
+		c.TTFCMs = ttfcMs.Int64
+		c.Chunks = int(chunks.Int64)
+		c.OutputLength = int(outputLength.Int64)
+		if createdAt.Valid {
+			c.CreatedAt = createdAt.Time
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
 // GetByID retrieves a single conversation by Message ID (or Turn ID)
 func (s *Store) GetByMsgID(id int64) (*ConversationTurn, error) {
 	query := `
-		SELECT msg_id, session_id, user_input, llm_response,
+		SELECT msg_id, session_id, user_input, llm_response, reasoning, status, routing,
 		       duration_ms, ttfc_ms, chunks, input_length, output_length,
 			   created_at
 		FROM chat_messages
@@ -219,6 +678,7 @@ func (s *Store) GetByMsgID(id int64) (*ConversationTurn, error) {
 
 	var turn ConversationTurn
 	var ttfcMs, chunks sql.NullInt64
+	var reasoning, routing sql.NullString
 	var createdAt sql.NullTime
 
 	err := s.db.QueryRow(query, id).Scan(
@@ -226,6 +686,9 @@ func (s *Store) GetByMsgID(id int64) (*ConversationTurn, error) {
 		&turn.SessionId,
 		&turn.UserInput,
 		&turn.ModelOutput,
+		&reasoning,
+		&turn.Status,
+		&routing,
 		&turn.DurationMs,
 		&ttfcMs,
 		&chunks,
@@ -240,6 +703,8 @@ func (s *Store) GetByMsgID(id int64) (*ConversationTurn, error) {
 		return nil, fmt.Errorf("failed to get chat message: %w", err)
 	}
 
+	turn.Reasoning = reasoning.String
+	turn.Routing = routing.String
 	if ttfcMs.Valid {
 		turn.TTFCMs = ttfcMs.Int64
 	}
@@ -261,7 +726,7 @@ func (s *Store) GetRecentMessages(limit int) ([]ConversationTurn, error) {
 	}
 
 	query := `
-		SELECT msg_id, session_id, user_input, llm_response,
+		SELECT msg_id, session_id, user_input, llm_response, reasoning, status, routing,
 		       duration_ms, ttfc_ms, chunks, input_length, output_length,
 		       created_at
 		FROM chat_messages
@@ -279,6 +744,7 @@ func (s *Store) GetRecentMessages(limit int) ([]ConversationTurn, error) {
 	for rows.Next() {
 		var turn ConversationTurn
 		var ttfcMs, chunks sql.NullInt64
+		var reasoning, routing sql.NullString
 		var createdAt sql.NullTime
 
 		if err := rows.Scan(
@@ -286,6 +752,9 @@ func (s *Store) GetRecentMessages(limit int) ([]ConversationTurn, error) {
 			&turn.SessionId,
 			&turn.UserInput,
 			&turn.ModelOutput,
+			&reasoning,
+			&turn.Status,
+			&routing,
 			&turn.DurationMs,
 			&ttfcMs,
 			&chunks,
@@ -296,6 +765,8 @@ func (s *Store) GetRecentMessages(limit int) ([]ConversationTurn, error) {
 			return nil, fmt.Errorf("failed to scan recent message: %w", err)
 		}
 
+		turn.Reasoning = reasoning.String
+		turn.Routing = routing.String
 		if ttfcMs.Valid {
 			turn.TTFCMs = ttfcMs.Int64
 		}
@@ -319,7 +790,7 @@ func (s *Store) GetRecentMessages(limit int) ([]ConversationTurn, error) {
 // GetByDateRange retrieves conversations within a date range
 func (s *Store) GetByDateRange(start, end time.Time) ([]ConversationTurn, error) {
 	query := `
-		SELECT msg_id, session_id, user_input, llm_response,
+		SELECT msg_id, session_id, user_input, llm_response, reasoning, status, routing,
 		       duration_ms, ttfc_ms, chunks, input_length, output_length,
 		       created_at
 		FROM chat_messages
@@ -337,6 +808,7 @@ func (s *Store) GetByDateRange(start, end time.Time) ([]ConversationTurn, error)
 	for rows.Next() {
 		var turn ConversationTurn
 		var ttfcMs, chunks sql.NullInt64
+		var reasoning, routing sql.NullString
 		var createdAt sql.NullTime
 
 		if err := rows.Scan(
@@ -344,6 +816,9 @@ func (s *Store) GetByDateRange(start, end time.Time) ([]ConversationTurn, error)
 			&turn.SessionId,
 			&turn.UserInput,
 			&turn.ModelOutput,
+			&reasoning,
+			&turn.Status,
+			&routing,
 			&turn.DurationMs,
 			&ttfcMs,
 			&chunks,
@@ -354,6 +829,8 @@ func (s *Store) GetByDateRange(start, end time.Time) ([]ConversationTurn, error)
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
 
+		turn.Reasoning = reasoning.String
+		turn.Routing = routing.String
 		if ttfcMs.Valid {
 			turn.TTFCMs = ttfcMs.Int64
 		}
@@ -381,7 +858,7 @@ func (s *Store) GetMessagesBySession(sessionID string, limit, offset int) ([]Con
 	}
 
 	query := `
-		SELECT msg_id, session_id, user_input, llm_response,
+		SELECT msg_id, session_id, user_input, llm_response, reasoning, status, routing,
 		       duration_ms, ttfc_ms, chunks, input_length, output_length,
 		       created_at
 		FROM chat_messages
@@ -400,6 +877,7 @@ func (s *Store) GetMessagesBySession(sessionID string, limit, offset int) ([]Con
 	for rows.Next() {
 		var turn ConversationTurn
 		var ttfcMs, chunks sql.NullInt64
+		var reasoning, routing sql.NullString
 		var createdAt sql.NullTime
 
 		if err := rows.Scan(
@@ -407,6 +885,9 @@ func (s *Store) GetMessagesBySession(sessionID string, limit, offset int) ([]Con
 			&turn.SessionId,
 			&turn.UserInput,
 			&turn.ModelOutput,
+			&reasoning,
+			&turn.Status,
+			&routing,
 			&turn.DurationMs,
 			&ttfcMs,
 			&chunks,
@@ -417,6 +898,8 @@ func (s *Store) GetMessagesBySession(sessionID string, limit, offset int) ([]Con
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
 
+		turn.Reasoning = reasoning.String
+		turn.Routing = routing.String
 		if ttfcMs.Valid {
 			turn.TTFCMs = ttfcMs.Int64
 		}
@@ -0,0 +1,187 @@
+package devserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/firebase/genkit/go/core/tracing"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// DefaultAddr is the address flows are served on, Genkit's usual flow server port
+const DefaultAddr = "localhost:3400"
+
+// maxRequestSize limits the body of flow requests
+const maxRequestSize = 10 << 20
+
+// localFields are flow input fields that act on this machine: tools read
+// files without asking in dev mode and image paths load local files. They
+// are only accepted from the chat itself, not over HTTP.
+var localFields = []string{"tools", "image_paths"}
+
+// defaultTraceLimit is the number of traces listed when no limit is given
+const defaultTraceLimit = 50
+
+// Server serves the registered Genkit flows over HTTP
+type Server struct {
+	g      *genkit.Genkit
+	addr   string
+	traces *TraceStore
+}
+
+// New creates a server for the flows of g that records traces in traceDir
+func New(g *genkit.Genkit, addr, traceDir string) (*Server, error) {
+	traces, err := NewTraceStore(traceDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{g: g, addr: addr, traces: traces}, nil
+}
+
+// Flows returns the names of the served flows
+func (s *Server) Flows() []string {
+	names := make([]string, 0)
+	for _, flow := range genkit.ListFlows(s.g) {
+		names = append(names, flow.Name())
+	}
+	return names
+}
+
+// Handler returns the HTTP handler serving the flows and traces:
+//
+//	POST /{flow}          runs a flow with {"data": input}; add ?stream=true or
+//	                      Accept: text/event-stream to stream chunks as SSE
+//	GET  /                lists the flows
+//	GET  /traces          lists recent traces, newest first (?limit=N)
+//	GET  /traces/{id}     returns a trace with all its spans
+//
+// Flow inputs with any of the localFields are rejected.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, flow := range genkit.ListFlows(s.g) {
+		mux.HandleFunc("POST /"+flow.Name(), rejectLocalFields(genkit.Handler(flow)))
+	}
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"flows": s.Flows()})
+	})
+	mux.HandleFunc("GET /traces", s.listTraces)
+	mux.HandleFunc("GET /traces/{id}", s.getTrace)
+	return mux
+}
+
+// Serve records traces and serves the flows until ctx is done
+func (s *Server) Serve(ctx context.Context) error {
+	// Traces are also sent to the Genkit developer UI when it started us
+	tracing.WriteTelemetryImmediate(s.traces)
+
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	slog.Info("Serving flows", "addr", s.addr, "flows", s.Flows(), "traces", s.traces.Dir())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// TraceDir returns the directory traces are saved in
+func (s *Server) TraceDir() string {
+	return s.traces.Dir()
+}
+
+func (s *Server) listTraces(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTraceLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	summaries, err := s.traces.List(limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{"traces": summaries})
+}
+
+func (s *Server) getTrace(w http.ResponseWriter, r *http.Request) {
+	trace, err := s.traces.Get(r.PathValue("id"))
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, trace)
+}
+
+// rejectLocalFields answers 400 Bad Request to flow inputs with any of the
+// localFields before they reach the flow
+func rejectLocalFields(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, "failed to read request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		// Malformed input is left to the flow handler to report
+		if json.Unmarshal(body, &req) == nil {
+			for _, field := range localFields {
+				if v, ok := req.Data[field]; ok && string(v) != "null" {
+					http.Error(w, fmt.Sprintf("%q is not accepted over HTTP", field), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// writeJSON writes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("Failed to write response", "error", err)
+	}
+}
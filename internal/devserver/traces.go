@@ -0,0 +1,159 @@
+package devserver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/firebase/genkit/go/core/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TraceStore saves Genkit traces as JSON files, one file per trace.
+// It implements tracing.TelemetryClient, so every flow run, model call and
+// tool call is recorded in the same format the Genkit developer UI uses.
+type TraceStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// TraceSummary describes a saved trace without its spans
+type TraceSummary struct {
+	TraceID     string  `json:"traceId"`
+	DisplayName string  `json:"displayName"`
+	StartTime   float64 `json:"startTime"`
+	DurationMs  float64 `json:"durationMs"`
+	Status      string  `json:"status"`
+}
+
+// NewTraceStore creates a trace store writing to dir
+func NewTraceStore(dir string) (*TraceStore, error) {
+	// Traces contain prompts and answers, so only the user may read them
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create traces directory: %w", err)
+	}
+	return &TraceStore{dir: dir}, nil
+}
+
+// Dir returns the directory the traces are saved in
+func (s *TraceStore) Dir() string {
+	return s.dir
+}
+
+// Save merges the spans of trace into its file. Spans of a trace are
+// exported as they finish, so a trace is saved several times.
+func (s *TraceStore) Save(ctx context.Context, trace *tracing.Data) error {
+	if trace == nil || trace.TraceID == "" {
+		return errors.New("trace without ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.load(trace.TraceID)
+	switch {
+	case err == nil:
+		for id, span := range trace.Spans {
+			existing.Spans[id] = span
+		}
+		// The root span finishes last and names the trace
+		if trace.DisplayName != "" {
+			existing.DisplayName = trace.DisplayName
+			existing.StartTime = trace.StartTime
+			existing.EndTime = trace.EndTime
+		}
+		trace = existing
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %w", err)
+	}
+	return os.WriteFile(s.path(trace.TraceID), data, 0600)
+}
+
+// Get returns a saved trace
+func (s *TraceStore) Get(traceID string) (*tracing.Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(traceID)
+}
+
+// List returns summaries of the saved traces, newest first
+func (s *TraceStore) List(limit int) ([]TraceSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TraceSummary, 0, len(files))
+	for _, file := range files {
+		trace, err := s.load(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, summarize(trace))
+	}
+
+	slices.SortFunc(summaries, func(a, b TraceSummary) int {
+		return cmp.Compare(b.StartTime, a.StartTime)
+	})
+	if limit > 0 && len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries, nil
+}
+
+// load reads a trace file; the caller holds the lock
+func (s *TraceStore) load(traceID string) (*tracing.Data, error) {
+	data, err := os.ReadFile(s.path(traceID))
+	if err != nil {
+		return nil, err
+	}
+	var trace tracing.Data
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return nil, fmt.Errorf("failed to parse trace %s: %w", traceID, err)
+	}
+	if trace.Spans == nil {
+		trace.Spans = make(map[string]*tracing.SpanData)
+	}
+	return &trace, nil
+}
+
+// path returns the file of a trace; IDs are hex strings, anything else is cleaned
+func (s *TraceStore) path(traceID string) string {
+	return filepath.Join(s.dir, filepath.Base(traceID)+".json")
+}
+
+// summarize reduces a trace to its root span
+func summarize(trace *tracing.Data) TraceSummary {
+	summary := TraceSummary{
+		TraceID:     trace.TraceID,
+		DisplayName: trace.DisplayName,
+		StartTime:   float64(trace.StartTime),
+		DurationMs:  float64(trace.EndTime - trace.StartTime),
+		Status:      "running",
+	}
+	for _, span := range trace.Spans {
+		if span.ParentSpanID != "" {
+			continue
+		}
+		summary.Status = "ok"
+		if span.Status.Code == uint32(codes.Error) {
+			summary.Status = "error"
+		}
+	}
+	return summary
+}
@@ -2,8 +2,11 @@ package flows
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"strings"
 	"time"
+	"unicode"
 
 	"tchat/internal/media"
 
@@ -14,25 +17,41 @@ import (
 
 // ChatFlow encapsulates the chat flow with its dependencies
 type ChatFlow struct {
-	genkit *genkit.Genkit
-	flow   *core.Flow[ChatRequest, ChatResponse, struct{}]
+	genkit        *genkit.Genkit
+	flow          *core.Flow[ChatRequest, ChatResponse, *ai.ModelResponseChunk]
+	defaultModel  string
+	resolvePrompt PromptResolver
+	middlewares   []Middleware
 }
 
 // NewChatFlow creates a new chat flow with dependencies
-func NewChatFlow(g *genkit.Genkit) *ChatFlow {
+func NewChatFlow(g *genkit.Genkit, opts ...Option) *ChatFlow {
 	cf := &ChatFlow{
 		genkit: g,
 	}
+	for _, opt := range opts {
+		opt(cf)
+	}
 
-	// Define the flow
-	cf.flow = genkit.DefineFlow(g, "chat-flow", cf.execute)
+	// Define the flow; it streams chunks when served over HTTP
+	cf.flow = genkit.DefineStreamingFlow(g, "chat-flow", cf.execute)
 
 	return cf
 }
 
-// execute is the main flow execution function (without streaming)
-func (cf *ChatFlow) execute(ctx context.Context, req ChatRequest) (ChatResponse, error) {
-	return cf.generate(ctx, req, nil)
+// WithDefaultModel sets the model used for requests without one
+func WithDefaultModel(model string) Option {
+	return func(cf *ChatFlow) {
+		cf.defaultModel = model
+	}
+}
+
+// execute is the main flow execution function, streaming when a callback is given
+func (cf *ChatFlow) execute(ctx context.Context, req ChatRequest, cb core.StreamCallback[*ai.ModelResponseChunk]) (ChatResponse, error) {
+	if req.Model == "" {
+		req.Model = cf.defaultModel
+	}
+	return cf.generate(ctx, req, StreamCallback(cb))
 }
 
 // generate handles the actual AI generation
@@ -40,6 +59,25 @@ func (cf *ChatFlow) generate(ctx context.Context, req ChatRequest, streamCallbac
 	response := ChatResponse{}
 	startTime := time.Now()
 
+	// Render dotprompt requests, honoring the prompt's model and config
+	if req.Prompt != "" {
+		if cf.resolvePrompt == nil {
+			response.Error = fmt.Errorf("prompts are not available")
+			return response, response.Error
+		}
+		resolved, err := cf.resolvePrompt(ctx, req)
+		if err != nil {
+			response.Error = err
+			return response, err
+		}
+		req = resolved
+	}
+
+	if err := cf.beforeRequest(ctx, &req); err != nil {
+		response.Error = err
+		return response, err
+	}
+
 	// Use model from request (required)
 	model := req.Model
 
@@ -49,7 +87,7 @@ func (cf *ChatFlow) generate(ctx context.Context, req ChatRequest, streamCallbac
 
 	// Build message list: prior history + current user turn
 	messages := make([]*ai.Message, 0, len(req.History)+1)
-	messages = append(messages, req.History...)
+	messages = append(messages, withoutReasoning(req.History)...)
 
 	// Handle multimodal message if images are provided
 	var currentMessage *ai.Message
@@ -88,7 +126,26 @@ func (cf *ChatFlow) generate(ctx context.Context, req ChatRequest, streamCallbac
 		ai.WithMessages(messages...),
 	}
 
-	// Add streaming handler if callback provided
+	// Pass model options such as temperature as generation config
+	if len(req.Options) > 0 {
+		opts = append(opts, ai.WithConfig(req.Options))
+	}
+
+	// Offer tools when requested; the caller checks the model supports them
+	if len(req.Tools) > 0 {
+		toolRefs := make([]ai.ToolRef, 0, len(req.Tools))
+		for _, name := range req.Tools {
+			toolRefs = append(toolRefs, ai.ToolName(name))
+		}
+		opts = append(opts, ai.WithTools(toolRefs...))
+	}
+
+	// Add streaming handler if callback provided.
+	// Thinking sections are delivered to the callback as reasoning parts.
+	// Streamed text is accumulated so a cancelled or failed generation
+	// still returns the partial answer.
+	splitter := &thinkingSplitter{}
+	var partialText, partialReasoning strings.Builder
 	if streamCallback != nil {
 		opts = append(opts, ai.WithStreaming(func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
 			if chunkCount == 0 {
@@ -96,12 +153,24 @@ func (cf *ChatFlow) generate(ctx context.Context, req ChatRequest, streamCallbac
 			}
 			//slog.Info("chunk callback", "chunk id", chunkCount)
 			chunkCount++
+			chunk, err := cf.filterChunk(ctx, splitter.splitChunk(chunk))
+			if err != nil || chunk == nil {
+				return err
+			}
+			for _, part := range chunk.Content {
+				switch {
+				case part.IsReasoning():
+					partialReasoning.WriteString(part.Text)
+				case part.IsText():
+					partialText.WriteString(part.Text)
+				}
+			}
 			return streamCallback(ctx, chunk)
 		}))
 	}
 
 	// Generate response
-	output, err := genkit.GenerateText(ctx, cf.genkit, opts...)
+	resp, err := genkit.Generate(ctx, cf.genkit, opts...)
 
 	duration := time.Since(startTime)
 	response.DurationMs = duration.Milliseconds()
@@ -112,15 +181,70 @@ func (cf *ChatFlow) generate(ctx context.Context, req ChatRequest, streamCallbac
 	}
 
 	if err != nil {
+		// Trailing whitespace is kept, so /continue joins words correctly
+		reasoning, answer := splitter.flush()
+		response.Output = strings.TrimLeftFunc(partialText.String()+answer, unicode.IsSpace)
+		response.Reasoning = strings.TrimSpace(partialReasoning.String() + reasoning)
 		response.Error = err
+		cf.afterResponse(ctx, req, &response)
 		return response, err
 	}
 
-	response.Output = output
+	// Keep reasoning out of the answer; models either send reasoning
+	// parts or inline <think> sections
+	reasoning, answer := SplitThinking(resp.Text())
+	response.Output = answer
+	response.Reasoning = strings.TrimSpace(resp.Reasoning() + reasoning)
+	response.ToolCalls = collectToolCalls(resp)
+	response.ToolMessages = toolMessages(resp)
+	cf.afterResponse(ctx, req, &response)
 	return response, nil
 }
 
-// Run executes the flow with the given request (no streaming support due to serialization)
+// collectToolCalls pairs the tool requests and responses exchanged during generation
+func collectToolCalls(resp *ai.ModelResponse) []ToolCall {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+
+	var calls []ToolCall
+	pending := make(map[string][]int) // tool name -> indexes of calls awaiting output
+	for _, msg := range resp.Request.Messages {
+		for _, part := range msg.Content {
+			switch {
+			case part.IsToolRequest():
+				name := part.ToolRequest.Name
+				pending[name] = append(pending[name], len(calls))
+				calls = append(calls, ToolCall{Name: name, Input: part.ToolRequest.Input})
+			case part.IsToolResponse():
+				name := part.ToolResponse.Name
+				if idx := pending[name]; len(idx) > 0 {
+					calls[idx[0]].Output = part.ToolResponse.Output
+					pending[name] = idx[1:]
+				}
+			}
+		}
+	}
+	return calls
+}
+
+// toolMessages returns the messages Genkit added after the user message
+// while calling tools: model messages with tool requests and the tool
+// responses
+func toolMessages(resp *ai.ModelResponse) []*ai.Message {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	msgs := resp.Request.Messages
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == ai.RoleUser {
+			return msgs[i+1:]
+		}
+	}
+	return nil
+}
+
+// Run executes the flow with the given request without streaming
 func (cf *ChatFlow) Run(ctx context.Context, req ChatRequest) (ChatResponse, error) {
 	return cf.flow.Run(ctx, req)
 }
@@ -0,0 +1,55 @@
+package flows
+
+import (
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// toolExchange is a response after one tool call, as Genkit returns it
+func toolExchange() *ai.ModelResponse {
+	toolReq := &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{
+		ai.NewToolRequestPart(&ai.ToolRequest{Name: "read_file", Input: map[string]any{"path": "go.mod"}}),
+	}}
+	toolResp := &ai.Message{Role: ai.RoleTool, Content: []*ai.Part{
+		ai.NewToolResponsePart(&ai.ToolResponse{Name: "read_file", Output: "module tchat"}),
+	}}
+	return &ai.ModelResponse{
+		Request: &ai.ModelRequest{Messages: []*ai.Message{
+			ai.NewSystemTextMessage("system"),
+			ai.NewUserTextMessage("earlier question"),
+			ai.NewModelTextMessage("earlier answer"),
+			ai.NewUserTextMessage("what module is this?"),
+			toolReq,
+			toolResp,
+		}},
+		Message: ai.NewModelTextMessage("It is tchat."),
+	}
+}
+
+func TestToolMessages(t *testing.T) {
+	msgs := toolMessages(toolExchange())
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].Role != ai.RoleModel || !msgs[0].Content[0].IsToolRequest() {
+		t.Errorf("first message is not the tool request: %+v", msgs[0])
+	}
+	if msgs[1].Role != ai.RoleTool || !msgs[1].Content[0].IsToolResponse() {
+		t.Errorf("second message is not the tool response: %+v", msgs[1])
+	}
+
+	if msgs := toolMessages(&ai.ModelResponse{Request: &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}}); len(msgs) != 0 {
+		t.Errorf("got %d tool messages without tool calls", len(msgs))
+	}
+}
+
+func TestCollectToolCalls(t *testing.T) {
+	calls := collectToolCalls(toolExchange())
+	if len(calls) != 1 {
+		t.Fatalf("got %d calls, want 1", len(calls))
+	}
+	if calls[0].Name != "read_file" || calls[0].Output != "module tchat" {
+		t.Errorf("got %+v", calls[0])
+	}
+}
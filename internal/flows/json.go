@@ -0,0 +1,177 @@
+package flows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// DefaultJSONAttempts is the number of times a JSON answer is requested before giving up
+const DefaultJSONAttempts = 3
+
+// FormatOption is the generation config key that constrains the output of
+// Ollama models: "json", or a JSON Schema the answer must match
+const FormatOption = "format"
+
+// AttemptCallback is called after each JSON attempt that failed validation
+type AttemptCallback func(attempt int, err error)
+
+// RunJSON asks the model for a JSON answer constrained to req.OutputSchema.
+// Answers are validated as well, since not every server enforces the format;
+// invalid answers are sent back to the model together with the validation
+// error until a valid answer is produced or maxAttempts is reached.
+func (cf *ChatFlow) RunJSON(ctx context.Context, req ChatRequest, maxAttempts int, onInvalid AttemptCallback) (ChatResponse, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultJSONAttempts
+	}
+
+	instructions, err := jsonInstructions(req.OutputSchema)
+	if err != nil {
+		return ChatResponse{Error: err}, err
+	}
+
+	// Ask the server to constrain the output; a copy keeps the caller's options intact
+	options := maps.Clone(req.Options)
+	if options == nil {
+		options = make(map[string]any, 1)
+	}
+	options[FormatOption] = "json"
+	if req.OutputSchema != nil {
+		options[FormatOption] = req.OutputSchema
+	}
+	req.Options = options
+
+	history := slices.Clone(req.History)
+	input := req.UserInput + "\n\n" + instructions
+	imagePaths := req.ImagePaths
+
+	var totalMs int64
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		attemptReq := req
+		attemptReq.UserInput = input
+		attemptReq.History = history
+		attemptReq.ImagePaths = imagePaths
+
+		resp, err := cf.generate(ctx, attemptReq, nil)
+		totalMs += resp.DurationMs
+		resp.DurationMs = totalMs
+		resp.Attempts = attempt
+		if err != nil {
+			return resp, err
+		}
+
+		value, err := ValidateJSON(resp.Output, req.OutputSchema)
+		if err == nil {
+			resp.JSON = value
+			return resp, nil
+		}
+
+		slog.Warn("Model returned invalid JSON", "attempt", attempt, "error", err)
+		if onInvalid != nil {
+			onInvalid(attempt, err)
+		}
+		lastErr = err
+
+		// Send the invalid answer back with the validation error
+		history = append(history, ai.NewUserTextMessage(input), ai.NewModelTextMessage(resp.Output))
+		input = fmt.Sprintf("Your previous response was not valid: %v\nRespond again with only the corrected JSON.", err)
+		imagePaths = nil
+	}
+
+	err = fmt.Errorf("no valid JSON after %d attempts: %w", maxAttempts, lastErr)
+	return ChatResponse{DurationMs: totalMs, Attempts: maxAttempts, Error: err}, err
+}
+
+// ValidateJSON extracts JSON from a model answer, checks it against an optional
+// schema and returns the decoded value
+func ValidateJSON(text string, schema map[string]any) (any, error) {
+	raw := extractJSON(text)
+
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if schema == nil {
+		return value, nil
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate JSON: %w", err)
+	}
+	if !result.Valid() {
+		msgs := make([]string, 0, len(result.Errors()))
+		for _, e := range result.Errors() {
+			msgs = append(msgs, e.String())
+		}
+		return nil, fmt.Errorf("does not match schema: %s", strings.Join(msgs, "; "))
+	}
+
+	return value, nil
+}
+
+// LoadJSONSchema reads a JSON Schema file and checks that it compiles
+func LoadJSONSchema(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema file: %w", err)
+	}
+
+	if _, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// jsonInstructions builds the instructions appended to the prompt in JSON mode
+func jsonInstructions(schema map[string]any) (string, error) {
+	if schema == nil {
+		return "Respond with valid JSON only, without any explanation or markdown.", nil
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return fmt.Sprintf("Respond with valid JSON only, without any explanation or markdown. "+
+		"The JSON must conform to the following JSON Schema:\n%s", b), nil
+}
+
+// extractJSON strips markdown code fences and surrounding prose from a model answer
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+
+	if start := strings.Index(text, "```"); start >= 0 {
+		rest := text[start+3:]
+		// Skip the language tag, if any
+		if nl := strings.Index(rest, "\n"); nl >= 0 {
+			rest = rest[nl+1:]
+		}
+		if end := strings.Index(rest, "```"); end >= 0 {
+			return strings.TrimSpace(rest[:end])
+		}
+	}
+
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start >= 0 && end > start {
+		return text[start : end+1]
+	}
+	return text
+}
@@ -0,0 +1,206 @@
+package flows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Middleware hooks into every generation of the ChatFlow. Any hook may be nil.
+// Middlewares run in the configured order for every stage.
+type Middleware struct {
+	Name string
+
+	// BeforeRequest may rewrite the request before it is sent to the model.
+	// Returning an error aborts the generation.
+	BeforeRequest func(ctx context.Context, req *ChatRequest) error
+
+	// FilterChunk may rewrite a streamed chunk before it is shown and
+	// accumulated. Returning a nil chunk drops it.
+	FilterChunk func(ctx context.Context, chunk *ai.ModelResponseChunk) (*ai.ModelResponseChunk, error)
+
+	// AfterResponse receives the final response, also when the generation
+	// failed, and may rewrite it
+	AfterResponse func(ctx context.Context, req ChatRequest, resp *ChatResponse)
+}
+
+// WithMiddleware appends middlewares to the chain of the ChatFlow
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(cf *ChatFlow) {
+		cf.middlewares = append(cf.middlewares, middlewares...)
+	}
+}
+
+// BuiltinMiddlewares lists the names accepted by NewMiddleware
+var BuiltinMiddlewares = []string{"datetime", "redact", "audit"}
+
+// NewMiddleware returns a built-in middleware by name:
+//   - datetime adds the current date and time to the system prompt
+//   - redact masks secrets such as API keys and private keys in answers
+//   - audit appends every request and response to audit.jsonl in appDir
+func NewMiddleware(name, appDir string) (Middleware, error) {
+	switch name {
+	case "datetime":
+		return dateTimeMiddleware(), nil
+	case "redact":
+		return redactMiddleware(), nil
+	case "audit":
+		return auditMiddleware(filepath.Join(appDir, "audit.jsonl")), nil
+	default:
+		return Middleware{}, fmt.Errorf("unknown middleware %q (available: %v)", name, BuiltinMiddlewares)
+	}
+}
+
+// beforeRequest runs the BeforeRequest hooks in order
+func (cf *ChatFlow) beforeRequest(ctx context.Context, req *ChatRequest) error {
+	for _, m := range cf.middlewares {
+		if m.BeforeRequest == nil {
+			continue
+		}
+		if err := m.BeforeRequest(ctx, req); err != nil {
+			return fmt.Errorf("middleware %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// filterChunk runs the FilterChunk hooks in order until one drops the chunk
+func (cf *ChatFlow) filterChunk(ctx context.Context, chunk *ai.ModelResponseChunk) (*ai.ModelResponseChunk, error) {
+	for _, m := range cf.middlewares {
+		if m.FilterChunk == nil {
+			continue
+		}
+		var err error
+		chunk, err = m.FilterChunk(ctx, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", m.Name, err)
+		}
+		if chunk == nil {
+			return nil, nil
+		}
+	}
+	return chunk, nil
+}
+
+// afterResponse runs the AfterResponse hooks in order
+func (cf *ChatFlow) afterResponse(ctx context.Context, req ChatRequest, resp *ChatResponse) {
+	for _, m := range cf.middlewares {
+		if m.AfterResponse != nil {
+			m.AfterResponse(ctx, req, resp)
+		}
+	}
+}
+
+// dateTimeMiddleware tells the model the current date and time
+func dateTimeMiddleware() Middleware {
+	return Middleware{
+		Name: "datetime",
+		BeforeRequest: func(ctx context.Context, req *ChatRequest) error {
+			now := time.Now().Format("Monday, 2 January 2006 15:04 MST")
+			if req.SystemPrompt != "" {
+				req.SystemPrompt += "\n\n"
+			}
+			req.SystemPrompt += "The current date and time is " + now + "."
+			return nil
+		},
+	}
+}
+
+// secretPatterns match common credentials that should not end up in answers or the database
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`),
+}
+
+// redact replaces secrets in text
+func redact(text string) string {
+	for _, re := range secretPatterns {
+		text = re.ReplaceAllString(text, "[REDACTED]")
+	}
+	return text
+}
+
+// redactMiddleware masks secrets in streamed chunks and in the final answer.
+// Secrets split across chunks may still be shown while streaming, but the
+// final answer that is saved and kept in history is always redacted.
+func redactMiddleware() Middleware {
+	return Middleware{
+		Name: "redact",
+		FilterChunk: func(ctx context.Context, chunk *ai.ModelResponseChunk) (*ai.ModelResponseChunk, error) {
+			for _, part := range chunk.Content {
+				if part.IsText() || part.IsReasoning() {
+					part.Text = redact(part.Text)
+				}
+			}
+			return chunk, nil
+		},
+		AfterResponse: func(ctx context.Context, req ChatRequest, resp *ChatResponse) {
+			resp.Output = redact(resp.Output)
+			resp.Reasoning = redact(resp.Reasoning)
+		},
+	}
+}
+
+// auditRecord is one line of the audit log
+type auditRecord struct {
+	Time       time.Time `json:"time"`
+	Model      string    `json:"model"`
+	UserInput  string    `json:"user_input"`
+	Output     string    `json:"output"`
+	DurationMs int64     `json:"duration_ms"`
+	Tools      []string  `json:"tools,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditMiddleware appends each generation to a JSON Lines file
+func auditMiddleware(path string) Middleware {
+	var mu sync.Mutex
+	return Middleware{
+		Name: "audit",
+		AfterResponse: func(ctx context.Context, req ChatRequest, resp *ChatResponse) {
+			record := auditRecord{
+				Time:       time.Now(),
+				Model:      req.Model,
+				UserInput:  req.UserInput,
+				Output:     resp.Output,
+				DurationMs: resp.DurationMs,
+			}
+			for _, call := range resp.ToolCalls {
+				record.Tools = append(record.Tools, call.Name)
+			}
+			if resp.Error != nil {
+				record.Error = resp.Error.Error()
+			}
+
+			line, err := json.Marshal(record)
+			if err != nil {
+				slog.Warn("Failed to marshal audit record", "error", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				slog.Warn("Failed to open audit log", "path", path, "error", err)
+				return
+			}
+			defer f.Close()
+			if _, err := f.Write(append(line, '\n')); err != nil {
+				slog.Warn("Failed to write audit log", "path", path, "error", err)
+			}
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package flows
+
+import "context"
+
+// PromptResolver renders the dotprompt named by req.Prompt into a plain
+// request: the rendered messages become the user input and system prompt,
+// and the model and config frontmatter of the prompt override the model
+// and options of the request.
+type PromptResolver func(ctx context.Context, req ChatRequest) (ChatRequest, error)
+
+type Option func(*ChatFlow)
+
+// WithPromptResolver sets the resolver used for requests naming a prompt
+func WithPromptResolver(resolve PromptResolver) Option {
+	return func(cf *ChatFlow) {
+		cf.resolvePrompt = resolve
+	}
+}
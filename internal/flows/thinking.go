@@ -0,0 +1,139 @@
+package flows
+
+import (
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkingSplitter separates <think>...</think> sections from streamed text.
+// Tags may be split across chunks, so a possible partial tag is held back
+// until the next chunk arrives.
+type thinkingSplitter struct {
+	inThink       bool
+	pending       string
+	answerStarted bool
+}
+
+// split returns the reasoning and answer text contained in a chunk of text
+func (s *thinkingSplitter) split(text string) (string, string) {
+	var reasoning, answer strings.Builder
+	buf := s.pending + text
+	s.pending = ""
+
+	for buf != "" {
+		tag := thinkOpenTag
+		if s.inThink {
+			tag = thinkCloseTag
+		}
+
+		if idx := strings.Index(buf, tag); idx >= 0 {
+			s.emit(&reasoning, &answer, buf[:idx])
+			buf = buf[idx+len(tag):]
+			s.inThink = !s.inThink
+			continue
+		}
+
+		// Hold back a trailing partial tag
+		for k := min(len(tag)-1, len(buf)); k > 0; k-- {
+			if strings.HasSuffix(buf, tag[:k]) {
+				s.pending = buf[len(buf)-k:]
+				buf = buf[:len(buf)-k]
+				break
+			}
+		}
+		s.emit(&reasoning, &answer, buf)
+		break
+	}
+
+	return reasoning.String(), answer.String()
+}
+
+// flush returns any text held back by split
+func (s *thinkingSplitter) flush() (string, string) {
+	var reasoning, answer strings.Builder
+	s.emit(&reasoning, &answer, s.pending)
+	s.pending = ""
+	return reasoning.String(), answer.String()
+}
+
+// emit appends text to the reasoning or answer depending on the current section.
+// Whitespace before the first answer text is dropped.
+func (s *thinkingSplitter) emit(reasoning, answer *strings.Builder, text string) {
+	if s.inThink {
+		reasoning.WriteString(text)
+		return
+	}
+	if !s.answerStarted {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		s.answerStarted = true
+	}
+	answer.WriteString(text)
+}
+
+// splitChunk rewrites the text parts of a streamed chunk into reasoning and text parts
+func (s *thinkingSplitter) splitChunk(chunk *ai.ModelResponseChunk) *ai.ModelResponseChunk {
+	parts := make([]*ai.Part, 0, len(chunk.Content))
+	for _, part := range chunk.Content {
+		if !part.IsText() {
+			parts = append(parts, part)
+			continue
+		}
+		reasoning, answer := s.split(part.Text)
+		if reasoning != "" {
+			parts = append(parts, ai.NewReasoningPart(reasoning, nil))
+		}
+		if answer != "" {
+			parts = append(parts, ai.NewTextPart(answer))
+		}
+	}
+
+	out := *chunk
+	out.Content = parts
+	return &out
+}
+
+// SplitThinking separates <think>...</think> sections from a complete answer
+func SplitThinking(text string) (string, string) {
+	s := &thinkingSplitter{}
+	reasoning, answer := s.split(text)
+	r, a := s.flush()
+	return strings.TrimSpace(reasoning + r), strings.TrimSpace(answer + a)
+}
+
+// withoutReasoning returns the messages with reasoning parts removed,
+// so earlier thinking is never sent back to the model
+func withoutReasoning(msgs []*ai.Message) []*ai.Message {
+	out := make([]*ai.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		hasReasoning := false
+		for _, part := range msg.Content {
+			if part.IsReasoning() {
+				hasReasoning = true
+				break
+			}
+		}
+		if !hasReasoning {
+			out = append(out, msg)
+			continue
+		}
+
+		clean := *msg
+		clean.Content = make([]*ai.Part, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			if !part.IsReasoning() {
+				clean.Content = append(clean.Content, part)
+			}
+		}
+		out = append(out, &clean)
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package flows
+
+import (
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestThinkingSplitterChunks(t *testing.T) {
+	tests := []struct {
+		name              string
+		chunks            []string
+		reasoning, answer string
+	}{
+		{"no thinking", []string{"Hello", " world"}, "", "Hello world"},
+		{"whole tags", []string{"<think>plan</think>", "\n\nAnswer"}, "plan", "Answer"},
+		{"open tag split", []string{"<th", "ink>plan</think>Answer"}, "plan", "Answer"},
+		{"close tag split", []string{"<think>pl", "an</thi", "nk> Answer"}, "plan", "Answer"},
+		{"one byte chunks", []string{"<", "t", "h", "i", "n", "k", ">", "x", "<", "/", "t", "h", "i", "n", "k", ">", "y"}, "x", "y"},
+		{"held back text is flushed", []string{"a <thi"}, "", "a <thi"},
+		{"unclosed thinking", []string{"<think>still thinking"}, "still thinking", ""},
+		{"less than is kept", []string{"a < b", " and c"}, "", "a < b and c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &thinkingSplitter{}
+			var reasoning, answer string
+			for _, c := range tt.chunks {
+				r, a := s.split(c)
+				reasoning += r
+				answer += a
+			}
+			r, a := s.flush()
+			reasoning += r
+			answer += a
+			if reasoning != tt.reasoning || answer != tt.answer {
+				t.Errorf("got reasoning %q, answer %q; want %q, %q", reasoning, answer, tt.reasoning, tt.answer)
+			}
+		})
+	}
+}
+
+func TestSplitThinking(t *testing.T) {
+	reasoning, answer := SplitThinking("<think>\nstep one\n</think>\n\nThe answer is 42.\n")
+	if reasoning != "step one" || answer != "The answer is 42." {
+		t.Errorf("got %q, %q", reasoning, answer)
+	}
+}
+
+func TestSplitChunkKeepsOtherParts(t *testing.T) {
+	s := &thinkingSplitter{}
+	tool := ai.NewToolRequestPart(&ai.ToolRequest{Name: "read_file"})
+	chunk := s.splitChunk(&ai.ModelResponseChunk{
+		Role:    ai.RoleModel,
+		Content: []*ai.Part{ai.NewTextPart("<think>why</think>because"), tool},
+	})
+
+	if len(chunk.Content) != 3 {
+		t.Fatalf("got %d parts, want 3", len(chunk.Content))
+	}
+	if !chunk.Content[0].IsReasoning() || chunk.Content[0].Text != "why" {
+		t.Errorf("first part = %+v, want reasoning %q", chunk.Content[0], "why")
+	}
+	if !chunk.Content[1].IsText() || chunk.Content[1].Text != "because" {
+		t.Errorf("second part = %+v, want text %q", chunk.Content[1], "because")
+	}
+	if chunk.Content[2] != tool {
+		t.Errorf("tool request part was not kept")
+	}
+}
@@ -11,19 +11,36 @@ type StreamCallback func(ctx context.Context, chunk *ai.ModelResponseChunk) erro
 
 // ChatRequest represents the input to the chat flow (must be serializable)
 type ChatRequest struct {
-	UserInput    string
-	Model        string
-	SystemPrompt string
-	History      []*ai.Message
-	ImagePaths   []string // Optional image paths for vision models
+	UserInput    string         `json:"user_input"`
+	Model        string         `json:"model,omitempty"`
+	SystemPrompt string         `json:"system_prompt,omitempty"`
+	History      []*ai.Message  `json:"history,omitempty"`
+	ImagePaths   []string       `json:"image_paths,omitempty"`   // Optional image paths for vision models
+	Tools        []string       `json:"tools,omitempty"`         // Optional tool names for models that support tool calling
+	OutputSchema map[string]any `json:"output_schema,omitempty"` // Optional JSON Schema used by RunJSON to validate answers
+	Options      map[string]any `json:"options,omitempty"`       // Optional model options using Ollama names, e.g. "temperature"
+	Prompt       string         `json:"prompt,omitempty"`        // Optional dotprompt name; rendered into UserInput by the PromptResolver
+	PromptInput  map[string]any `json:"prompt_input,omitempty"`  // Variables of the dotprompt
 }
 
 // ChatResponse represents the output from the chat flow
 type ChatResponse struct {
-	Output       string
-	DurationMs   int64
-	TTFCMs       int64
-	Chunks       int
-	Error        error
-	ImagesLoaded int // Number of images successfully loaded
+	Output       string        `json:"output"`    // Answer text; partial if the generation was cancelled or failed
+	Reasoning    string        `json:"reasoning"` // Thinking output of reasoning models, kept separate from Output
+	DurationMs   int64         `json:"duration_ms"`
+	TTFCMs       int64         `json:"ttfc_ms"`
+	Chunks       int           `json:"chunks"`
+	Error        error         `json:"-"`
+	ImagesLoaded int           `json:"images_loaded,omitempty"` // Number of images successfully loaded
+	ToolCalls    []ToolCall    `json:"tool_calls,omitempty"`    // Tool calls made while generating the response
+	ToolMessages []*ai.Message `json:"-"`                       // Tool requests and responses exchanged before the answer, in order
+	JSON         any           `json:"json,omitempty"`          // Validated JSON answer, set by RunJSON
+	Attempts     int           `json:"attempts,omitempty"`      // Number of attempts made by RunJSON
+}
+
+// ToolCall represents a single tool invocation and its result
+type ToolCall struct {
+	Name   string `json:"name"`
+	Input  any    `json:"input"`
+	Output any    `json:"output"`
 }
@@ -65,6 +65,20 @@ func (h *HistoryManager) AddAssistantMessage(model, text string) {
 	h.Add(model, ai.NewModelTextMessage(text))
 }
 
+// ReplaceLast replaces the last message if it has the same role as msg.
+// Returns false if history is empty or the roles differ.
+func (h *HistoryManager) ReplaceLast(msg *ai.Message) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := len(h.messages)
+	if n == 0 || h.messages[n-1].Role != msg.Role {
+		return false
+	}
+	h.messages[n-1] = msg
+	return true
+}
+
 // GetAll returns all messages for a model
 func (h *HistoryManager) GetAll() []*ai.Message {
 	h.mu.RLock()
@@ -0,0 +1,148 @@
+// Package httpclient configures the HTTP transport shared by all outbound
+// calls: Ollama, OpenAI-compatible servers and image downloads. Callers use
+// its clients or transport explicitly; http.DefaultTransport is left alone.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout limits requests made with Default until Configure sets another
+const DefaultTimeout = 30 * time.Second
+
+// Config describes the transport and the default client
+type Config struct {
+	Timeout        time.Duration     // limits requests made with Default; 0 for none
+	ConnectTimeout time.Duration     // limits connecting, including the TLS handshake; 0 for the Go default
+	Headers        map[string]string // added to requests to Hosts, e.g. Authorization
+	Hosts          []string          // base URLs of the model servers
+	CAFile         string            // PEM bundle trusted in addition to the system roots
+	Proxy          string            // proxy URL; HTTP_PROXY and HTTPS_PROXY otherwise
+}
+
+var (
+	// baseTransport is Go's default transport, the template of the configured one
+	baseTransport = http.DefaultTransport.(*http.Transport)
+
+	mu              sync.RWMutex
+	sharedTransport http.RoundTripper = baseTransport
+	defaultClient                     = &http.Client{Transport: baseTransport, Timeout: DefaultTimeout}
+	streamingClient                   = &http.Client{Transport: baseTransport}
+)
+
+// Configure builds the shared transport and the clients using it. Call it
+// at startup, before any request is made.
+func Configure(cfg Config) error {
+	transport := baseTransport.Clone()
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = cfg.ConnectTimeout
+	}
+	if cfg.CAFile != "" {
+		pool, err := loadCertPool(cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	var rt http.RoundTripper = transport
+	if len(cfg.Headers) > 0 {
+		hosts := make(map[string]bool, len(cfg.Hosts))
+		for _, h := range cfg.Hosts {
+			u, err := url.Parse(h)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("invalid host URL %q", h)
+			}
+			hosts[u.Host] = true
+		}
+		rt = &headerTransport{base: transport, headers: cfg.Headers, hosts: hosts}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sharedTransport = rt
+	defaultClient = &http.Client{Transport: rt, Timeout: cfg.Timeout}
+	streamingClient = &http.Client{Transport: rt}
+	return nil
+}
+
+// Transport returns the shared transport, for clients with their own
+// timeout such as those of the chat generators
+func Transport() http.RoundTripper {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sharedTransport
+}
+
+// Default returns the client for requests whose response is not streamed,
+// limited by the configured timeout
+func Default() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultClient
+}
+
+// Streaming returns the client without a timeout for streamed responses,
+// such as pulling a model, and requests that may take long, such as
+// loading a model. Their context bounds them.
+func Streaming() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return streamingClient
+}
+
+// loadCertPool returns the system roots with the certificates of a PEM file added
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", path)
+	}
+	return pool, nil
+}
+
+// headerTransport adds headers to requests to the model servers only, so
+// tokens are not sent along with image downloads
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+	hosts   map[string]bool // host:port of the model servers
+}
+
+// RoundTrip implements http.RoundTripper. Headers set on the request, such
+// as the API key of an OpenAI-compatible server, take precedence.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hosts[req.URL.Host] {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"tchat/internal/httpclient"
+
 	"github.com/firebase/genkit/go/ai"
 )
 
@@ -90,7 +92,7 @@ func loadImageFromFile(path string) (*ImageReference, error) {
 
 // loadImageFromURL downloads an image from a URL
 func loadImageFromURL(url string) (*ImageReference, error) {
-	resp, err := http.Get(url)
+	resp, err := httpclient.Default().Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
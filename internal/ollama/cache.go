@@ -0,0 +1,110 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ModelCache stores the capabilities reported by /api/show on disk, keyed by
+// model digest, so unchanged models are not queried again at startup. It is
+// shared by the Ollama hosts.
+type ModelCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]cachedModel
+	listed  map[string][]string // digests by host from the last listing
+}
+
+// cachedModel is the cached /api/show information of one model
+type cachedModel struct {
+	Name         string   `json:"name"`
+	Host         string   `json:"host"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// LoadModelCache reads the cache file; a missing or unreadable file gives an
+// empty cache. An empty path disables caching.
+func LoadModelCache(path string) *ModelCache {
+	c := &ModelCache{path: path, entries: make(map[string]cachedModel), listed: make(map[string][]string)}
+	if path == "" {
+		return c
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to read model cache", "path", path, "error", err)
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		slog.Warn("Ignoring invalid model cache", "path", path, "error", err)
+		c.entries = make(map[string]cachedModel)
+	}
+	return c
+}
+
+// get returns the cached capabilities of a model digest
+func (c *ModelCache) get(digest string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[digest]
+	if !ok || digest == "" {
+		return nil, false
+	}
+	return slices.Clone(entry.Capabilities), true
+}
+
+// put records the capabilities of a model digest listed by a host
+func (c *ModelCache) put(host, digest, name string, caps []string) {
+	if digest == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[digest] = cachedModel{Name: name, Host: host, Capabilities: slices.Clone(caps)}
+}
+
+// retain records the digests a host listed and writes the cache. Entries
+// of the host that it no longer lists are dropped, unless another host
+// lists them, so removed models do not accumulate. Entries of other hosts
+// are kept for when they are reachable.
+func (c *ModelCache) retain(host string, digests []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listed[host] = digests
+	if c.path == "" {
+		return nil
+	}
+
+	for digest, entry := range c.entries {
+		if entry.Host == host && !c.isListed(digest) {
+			delete(c.entries, digest)
+		}
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal model cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+	return nil
+}
+
+// isListed reports whether a host listed the digest; c.mu must be held
+func (c *ModelCache) isListed(digest string) bool {
+	for _, digests := range c.listed {
+		if slices.Contains(digests, digest) {
+			return true
+		}
+	}
+	return false
+}
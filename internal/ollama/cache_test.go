@@ -0,0 +1,56 @@
+package ollama
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model_cache.json")
+	c := LoadModelCache(path)
+	c.put("laptop", "d1", "llama3.2:3b", []string{"completion", "tools"})
+	c.put("laptop", "d2", "qwen3:8b", []string{"completion", "thinking"})
+	c.put("server", "d3", "nomic-embed-text:latest", []string{"embedding"})
+	c.put("laptop", "", "unknown:latest", []string{"completion"})
+
+	if _, ok := c.get(""); ok {
+		t.Errorf("models without digest are cached")
+	}
+	caps, ok := c.get("d1")
+	if !ok || len(caps) != 2 || caps[1] != "tools" {
+		t.Errorf("get(d1) = %v, %v", caps, ok)
+	}
+	caps[0] = "changed"
+	if caps, _ := c.get("d1"); caps[0] != "completion" {
+		t.Errorf("the cache shares its slices with callers")
+	}
+
+	// A host's entries it no longer lists are pruned; entries of hosts
+	// not listed yet are kept
+	if err := c.retain("laptop", []string{"d1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := LoadModelCache(path)
+	for digest, want := range map[string]bool{"d1": true, "d2": false, "d3": true} {
+		if _, ok := loaded.get(digest); ok != want {
+			t.Errorf("after reload, %s cached = %v, want %v", digest, ok, want)
+		}
+	}
+}
+
+func TestLoadModelCacheInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model_cache.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := LoadModelCache(path)
+	if _, ok := c.get("d1"); ok {
+		t.Errorf("invalid cache file gave entries")
+	}
+	c.put("laptop", "d1", "llama3.2:3b", []string{"completion"})
+	if err := c.retain("laptop", []string{"d1"}); err != nil {
+		t.Errorf("rewriting an invalid cache file: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"tchat/internal/httpclient"
+)
+
+// CreateRequest describes a model built from an existing one (/api/create)
+type CreateRequest struct {
+	Model      string         `json:"model"`
+	From       string         `json:"from"`
+	System     string         `json:"system,omitempty"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+	Stream     bool           `json:"stream"`
+}
+
+// Modelfile renders the request as the equivalent Modelfile, to show the
+// user what is created. Create sends the request as JSON, not the Modelfile.
+func (r CreateRequest) Modelfile() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "FROM %s\n", r.From)
+	if r.System != "" {
+		// A Modelfile cannot quote """ inside a triple-quoted string
+		system := r.System
+		if strings.Contains(system, `"""`) {
+			sb.WriteString("# The system prompt contains \"\"\", escaped here; it is sent unchanged\n")
+			system = strings.ReplaceAll(system, `"""`, `\"\"\"`)
+		}
+		fmt.Fprintf(&sb, "SYSTEM \"\"\"%s\"\"\"\n", system)
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Parameters)) {
+		switch v := r.Parameters[name].(type) {
+		case []string:
+			for _, s := range v {
+				fmt.Fprintf(&sb, "PARAMETER %s %q\n", name, s)
+			}
+		default:
+			fmt.Fprintf(&sb, "PARAMETER %s %v\n", name, v)
+		}
+	}
+	return sb.String()
+}
+
+// Create builds a model on the Ollama server, reporting progress to
+// onProgress the same way as Pull until the model is created
+func Create(ctx context.Context, serverAddress string, req CreateRequest, onProgress PullCallback) error {
+	req.Model = ModelName(req.Model)
+	req.Stream = true
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverAddress+"/api/create", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.Streaming().Do(httpReq)
+	if err != nil {
+		return classifyRequestError(fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return readProgress(resp.Body, "create", onProgress)
+}
@@ -0,0 +1,34 @@
+package ollama
+
+import "testing"
+
+func TestModelfile(t *testing.T) {
+	req := CreateRequest{
+		From:   "llama3.2:3b",
+		System: "You are terse.",
+		Parameters: map[string]any{
+			"temperature": 0.2,
+			"stop":        []string{"<|eot|>", "END"},
+		},
+	}
+	want := `FROM llama3.2:3b
+SYSTEM """You are terse."""
+PARAMETER stop "<|eot|>"
+PARAMETER stop "END"
+PARAMETER temperature 0.2
+`
+	if got := req.Modelfile(); got != want {
+		t.Errorf("Modelfile() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestModelfileEscapesTripleQuotes(t *testing.T) {
+	req := CreateRequest{From: "llama3.2:3b", System: `Answer in """quoted""" blocks`}
+	want := `FROM llama3.2:3b
+# The system prompt contains """, escaped here; it is sent unchanged
+SYSTEM """Answer in \"\"\"quoted\"\"\" blocks"""
+`
+	if got := req.Modelfile(); got != want {
+		t.Errorf("Modelfile() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"tchat/internal/httpclient"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// embedRequest represents the request to the Ollama embed API (/api/embed)
+type embedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive any      `json:"keep_alive,omitempty"`
+}
+
+// embedResponse represents the response from the Ollama embed API
+type embedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// IsEmbedding reports whether capabilities describe an embedding model,
+// one that cannot chat. Unknown capabilities count as a chat model.
+func IsEmbedding(caps []string) bool {
+	return slices.Contains(caps, "embedding") && !slices.Contains(caps, "completion")
+}
+
+// registerEmbedder defines an embedding model of the server with Genkit as
+// an embedder named "<host>/<model>". Returns the model identifier.
+func (p *Provider) registerEmbedder(g *genkit.Genkit, modelName string, caps []string) string {
+	id := p.name + "/" + modelName
+	setCapabilities(id, caps)
+
+	if genkit.LookupEmbedder(g, id) != nil {
+		slog.Info("Ollama embedder already registered", "name", id)
+		return id
+	}
+
+	opts := &ai.EmbedderOptions{
+		Label:    id,
+		Supports: &ai.EmbedderSupports{Input: []string{"text"}},
+	}
+	genkit.DefineEmbedder(g, api.NewName(p.name, modelName), opts, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		return embed(ctx, p.address, id, modelName, req)
+	})
+
+	slog.Info("Registered Ollama embedder", "name", id, "address", p.address)
+	return id
+}
+
+// embed embeds the text of each document with one /api/embed request
+func embed(ctx context.Context, serverAddress, id, modelName string, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+	embedReq := embedRequest{
+		Model:     modelName,
+		Input:     make([]string, 0, len(req.Input)),
+		KeepAlive: keepAliveValue(KeepAlive(id)),
+	}
+	for _, doc := range req.Input {
+		var text strings.Builder
+		for _, part := range doc.Content {
+			if part.IsText() {
+				text.WriteString(part.Text)
+			}
+		}
+		embedReq.Input = append(embedReq.Input, text.String())
+	}
+
+	payload, err := json.Marshal(embedReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverAddress+"/api/embed", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.Streaming().Do(httpReq)
+	if err != nil {
+		return nil, classifyRequestError(fmt.Errorf("embed request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp)
+	}
+
+	var embedResp embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	embeddings := make([]*ai.Embedding, 0, len(embedResp.Embeddings))
+	for _, e := range embedResp.Embeddings {
+		embeddings = append(embeddings, &ai.Embedding{Embedding: e})
+	}
+	return &ai.EmbedResponse{Embeddings: embeddings}, nil
+}
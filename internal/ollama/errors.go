@@ -0,0 +1,142 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"tchat/internal/provider"
+)
+
+// ErrorKind classifies errors returned by the Ollama API
+type ErrorKind int
+
+const (
+	KindUnknown       ErrorKind = iota
+	KindConnection              // Ollama is not reachable
+	KindModelNotFound           // The model is not pulled
+	KindOutOfMemory             // The model does not fit into memory
+	KindTimeout                 // The request took too long
+	KindBusy                    // Ollama is overloaded or restarting
+)
+
+// String returns a short description of the error kind
+func (k ErrorKind) String() string {
+	switch k {
+	case KindConnection:
+		return "cannot connect to Ollama"
+	case KindModelNotFound:
+		return "model not found"
+	case KindOutOfMemory:
+		return "out of memory"
+	case KindTimeout:
+		return "request timed out"
+	case KindBusy:
+		return "Ollama is busy"
+	default:
+		return "Ollama error"
+	}
+}
+
+// Error is a classified Ollama error
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int // HTTP status code, 0 if no response was received
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes connection errors match provider.ErrUnreachable
+func (e *Error) Is(target error) bool {
+	return target == provider.ErrUnreachable && e.Kind == KindConnection
+}
+
+// Transient reports whether retrying the request may succeed
+func (e *Error) Transient() bool {
+	return e.Kind == KindConnection || e.Kind == KindBusy
+}
+
+// Remediation suggests how the user can fix the error
+func (e *Error) Remediation(serverAddress, model string) string {
+	switch e.Kind {
+	case KindConnection:
+		return fmt.Sprintf("Check that Ollama is running (ollama serve) and reachable at %s, or set OLLAMA_HOST", serverAddress)
+	case KindModelNotFound:
+		// /pull reaches the model's host, which may not be this machine
+		return fmt.Sprintf("Pull the model with: /pull %s", model)
+	case KindOutOfMemory:
+		return "Try a smaller or more quantized model, or lower the context size (num_ctx)"
+	case KindTimeout:
+		return "The model may still be loading or is too slow on this machine; try again or use a smaller model"
+	case KindBusy:
+		return "Wait a moment and try again"
+	default:
+		return ""
+	}
+}
+
+// AsError returns the classified Ollama error in err's chain, if any
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// classifyRequestError classifies an error from sending a request to Ollama
+func classifyRequestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	kind := KindUnknown
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		kind = KindConnection
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		kind = KindTimeout
+	default:
+		var opErr *net.OpError
+		var dnsErr *net.DNSError
+		if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+			kind = KindConnection
+		}
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// classifyStatus classifies a non-200 response of the Ollama API
+func classifyStatus(statusCode int, message string) ErrorKind {
+	lower := strings.ToLower(message)
+	switch {
+	case statusCode == http.StatusNotFound || strings.Contains(lower, "not found"):
+		return KindModelNotFound
+	case strings.Contains(lower, "memory"):
+		return KindOutOfMemory
+	case statusCode == http.StatusServiceUnavailable || statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusBadGateway || strings.Contains(lower, "busy"):
+		return KindBusy
+	case statusCode == http.StatusGatewayTimeout || strings.Contains(lower, "timed out"):
+		return KindTimeout
+	default:
+		return KindUnknown
+	}
+}
+
+// classifyMessage classifies an error reported inside a streamed response
+func classifyMessage(message string) error {
+	return &Error{Kind: classifyStatus(0, message), Err: errors.New(message)}
+}
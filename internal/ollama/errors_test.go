@@ -0,0 +1,82 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"tchat/internal/provider"
+)
+
+func TestClassifyMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    ErrorKind
+	}{
+		{`model "llama9" not found, try pulling it first`, KindModelNotFound},
+		{"model requires more system memory (12 GiB) than is available (8 GiB)", KindOutOfMemory},
+		{"server busy, please try again", KindBusy},
+		{"the request timed out", KindTimeout},
+		{"unexpected EOF", KindUnknown},
+	}
+	for _, tt := range tests {
+		err := classifyMessage(tt.message)
+		oe, ok := AsError(err)
+		if !ok {
+			t.Fatalf("classifyMessage(%q) = %T, want *Error", tt.message, err)
+		}
+		if oe.Kind != tt.want {
+			t.Errorf("classifyMessage(%q).Kind = %v, want %v", tt.message, oe.Kind, tt.want)
+		}
+		if oe.Err.Error() != tt.message {
+			t.Errorf("classifyMessage(%q) wraps %q", tt.message, oe.Err)
+		}
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorKind
+	}{
+		{http.StatusNotFound, KindModelNotFound},
+		{http.StatusServiceUnavailable, KindBusy},
+		{http.StatusTooManyRequests, KindBusy},
+		{http.StatusBadGateway, KindBusy},
+		{http.StatusGatewayTimeout, KindTimeout},
+		{http.StatusInternalServerError, KindUnknown},
+	}
+	for _, tt := range tests {
+		if got := classifyStatus(tt.status, ""); got != tt.want {
+			t.Errorf("classifyStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyRequestError(t *testing.T) {
+	refused := classifyRequestError(fmt.Errorf("failed to send request: %w", syscall.ECONNREFUSED))
+	if oe, ok := AsError(refused); !ok || oe.Kind != KindConnection || !oe.Transient() {
+		t.Errorf("connection refused classified as %v", refused)
+	}
+	if !errors.Is(refused, provider.ErrUnreachable) {
+		t.Errorf("connection refused does not match provider.ErrUnreachable")
+	}
+
+	timeout := classifyRequestError(fmt.Errorf("failed to send request: %w", context.DeadlineExceeded))
+	if oe, ok := AsError(timeout); !ok || oe.Kind != KindTimeout || oe.Transient() {
+		t.Errorf("deadline exceeded classified as %v", timeout)
+	}
+	if errors.Is(timeout, provider.ErrUnreachable) {
+		t.Errorf("timeout matches provider.ErrUnreachable")
+	}
+
+	// Cancellation by the user is not an Ollama error
+	if err := classifyRequestError(context.Canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancellation classified as %v", err)
+	} else if _, ok := AsError(err); ok {
+		t.Errorf("cancellation was wrapped in an Ollama error")
+	}
+}
@@ -0,0 +1,447 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+const (
+	// maxAttempts is the number of times a chat request is sent when Ollama fails transiently
+	maxAttempts = 3
+
+	// retryDelay is the delay before the first retry; it doubles with every retry
+	retryDelay = 500 * time.Millisecond
+
+	// chatTimeout limits how long a chat request may take, including loading the model
+	chatTimeout = 5 * time.Minute
+)
+
+// chatMessage is a message of the Ollama chat API (/api/chat)
+type chatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+}
+
+type toolCall struct {
+	Function struct {
+		Name      string `json:"name"`
+		Arguments any    `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// chatRequest represents the request to the Ollama chat API (/api/chat)
+type chatRequest struct {
+	Model     string          `json:"model"`
+	Messages  []*chatMessage  `json:"messages"`
+	Tools     []chatTool      `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Format    json.RawMessage `json:"format,omitempty"` // "json" or a JSON Schema
+	Options   map[string]any  `json:"options,omitempty"`
+	KeepAlive any             `json:"keep_alive,omitempty"`
+}
+
+// chatResponse represents a response (or streamed chunk) of the Ollama chat API
+type chatResponse struct {
+	Model           string      `json:"model"`
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+	Error           string      `json:"error"`
+}
+
+// chatGenerator generates responses for one model with the Ollama chat API.
+// Unlike the Genkit Ollama plugin it passes the generation config as model
+// options and returns thinking output as reasoning parts.
+type chatGenerator struct {
+	serverAddress string
+	model         string // model name known to Ollama
+	id            string // model identifier with host prefix
+	client        *http.Client
+}
+
+// defineModel registers a model of the server with Genkit backed by
+// chatGenerator, named "<host>/<model>"
+func (p *Provider) defineModel(g *genkit.Genkit, modelName string, timeout time.Duration, opts *ai.ModelOptions) ai.Model {
+	gen := &chatGenerator{
+		serverAddress: p.address,
+		model:         modelName,
+		id:            p.name + "/" + modelName,
+		client:        &http.Client{Transport: p.transport, Timeout: timeout},
+	}
+	return genkit.DefineModel(g, api.NewName(p.name, modelName), opts, gen.generate)
+}
+
+// generate implements ai.ModelFunc
+func (cg *chatGenerator) generate(ctx context.Context, input *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+	req := chatRequest{
+		Model:     cg.model,
+		Stream:    cb != nil,
+		KeepAlive: keepAliveValue(KeepAlive(cg.id)),
+	}
+
+	for _, m := range input.Messages {
+		msgs, err := toChatMessages(m)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, msgs...)
+	}
+
+	for _, t := range input.Tools {
+		tool := chatTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.InputSchema
+		req.Tools = append(req.Tools, tool)
+	}
+
+	options, err := buildOptions(input.Config)
+	if err != nil {
+		return nil, err
+	}
+	// The output format is a request field, not a model option
+	if format, ok := options["format"]; ok {
+		if req.Format, err = json.Marshal(format); err != nil {
+			return nil, fmt.Errorf("failed to marshal output format: %w", err)
+		}
+		delete(options, "format")
+	}
+	req.Options = options
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := cg.post(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &ai.ModelResponse{
+		Request: input,
+		Message: &ai.Message{Role: ai.RoleModel},
+	}
+
+	if cb == nil {
+		var chatResp chatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chatResp.Error != "" {
+			return nil, classifyMessage(chatResp.Error)
+		}
+		response.Message.Content = toParts(chatResp.Message)
+		finish(response, chatResp)
+		return response, nil
+	}
+
+	// Streaming: Ollama sends one JSON object per line
+	var reasoning, text strings.Builder
+	var toolRequests []*ai.Part
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var chunkResp chatResponse
+		if err := json.Unmarshal(line, &chunkResp); err != nil {
+			return nil, fmt.Errorf("failed to decode chunk: %w", err)
+		}
+		if chunkResp.Error != "" {
+			return nil, classifyMessage(chunkResp.Error)
+		}
+
+		parts := toParts(chunkResp.Message)
+		for _, p := range parts {
+			switch {
+			case p.IsReasoning():
+				reasoning.WriteString(p.Text)
+			case p.IsText():
+				text.WriteString(p.Text)
+			case p.IsToolRequest():
+				toolRequests = append(toolRequests, p)
+			}
+		}
+		if len(parts) > 0 {
+			if err := cb(ctx, &ai.ModelResponseChunk{Role: ai.RoleModel, Content: parts}); err != nil {
+				return nil, err
+			}
+		}
+
+		if chunkResp.Done {
+			finish(response, chunkResp)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, classifyRequestError(fmt.Errorf("reading response stream: %w", err))
+	}
+
+	if reasoning.Len() > 0 {
+		response.Message.Content = append(response.Message.Content, ai.NewReasoningPart(reasoning.String(), nil))
+	}
+	if text.Len() > 0 {
+		response.Message.Content = append(response.Message.Content, ai.NewTextPart(text.String()))
+	}
+	response.Message.Content = append(response.Message.Content, toolRequests...)
+	if response.FinishReason == "" {
+		response.FinishReason = ai.FinishReasonUnknown
+	}
+
+	return response, nil
+}
+
+// post sends a chat request. Transient failures such as a refused
+// connection while Ollama restarts are retried with exponential backoff.
+// Nothing has been streamed at this point, so retrying is safe.
+func (cg *chatGenerator) post(ctx context.Context, payload []byte) (*http.Response, error) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := cg.postOnce(ctx, payload)
+		if err == nil {
+			return resp, nil
+		}
+
+		oe, ok := AsError(err)
+		if !ok || !oe.Transient() || attempt == maxAttempts {
+			return nil, err
+		}
+		slog.Warn("Retrying Ollama request", "model", cg.model, "attempt", attempt, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postOnce sends a chat request and checks the response status
+func (cg *chatGenerator) postOnce(ctx context.Context, payload []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cg.serverAddress+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := cg.client.Do(httpReq)
+	if err != nil {
+		return nil, classifyRequestError(fmt.Errorf("failed to send request: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp, nil
+}
+
+// finish copies the completion details of the final response
+func finish(response *ai.ModelResponse, chatResp chatResponse) {
+	switch chatResp.DoneReason {
+	case "length":
+		response.FinishReason = ai.FinishReasonLength
+	default:
+		response.FinishReason = ai.FinishReasonStop
+	}
+	response.Usage = &ai.GenerationUsage{
+		InputTokens:  chatResp.PromptEvalCount,
+		OutputTokens: chatResp.EvalCount,
+		TotalTokens:  chatResp.PromptEvalCount + chatResp.EvalCount,
+	}
+}
+
+// toChatMessages converts a Genkit message to Ollama chat messages.
+// Each tool response becomes its own "tool" message.
+func toChatMessages(m *ai.Message) ([]*chatMessage, error) {
+	msg := &chatMessage{Role: chatRole(m.Role)}
+	var toolMsgs []*chatMessage
+	var content strings.Builder
+
+	for _, part := range m.Content {
+		switch {
+		case part.IsText():
+			content.WriteString(part.Text)
+		case part.IsReasoning():
+			// Earlier thinking is not sent back to the model
+		case part.IsMedia():
+			data, err := mediaData(part.Text)
+			if err != nil {
+				return nil, err
+			}
+			msg.Images = append(msg.Images, data)
+		case part.IsToolRequest():
+			var call toolCall
+			call.Function.Name = part.ToolRequest.Name
+			call.Function.Arguments = part.ToolRequest.Input
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		case part.IsToolResponse():
+			output, err := json.Marshal(part.ToolResponse.Output)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal tool response: %w", err)
+			}
+			toolMsgs = append(toolMsgs, &chatMessage{
+				Role:     "tool",
+				Content:  string(output),
+				ToolName: part.ToolResponse.Name,
+			})
+		}
+	}
+
+	if len(toolMsgs) > 0 && content.Len() == 0 && len(msg.ToolCalls) == 0 {
+		return toolMsgs, nil
+	}
+	msg.Content = content.String()
+	return append([]*chatMessage{msg}, toolMsgs...), nil
+}
+
+// toParts converts an Ollama chat message to Genkit parts
+func toParts(msg chatMessage) []*ai.Part {
+	var parts []*ai.Part
+	if msg.Thinking != "" {
+		parts = append(parts, ai.NewReasoningPart(msg.Thinking, nil))
+	}
+	if msg.Content != "" {
+		parts = append(parts, ai.NewTextPart(msg.Content))
+	}
+	for _, call := range msg.ToolCalls {
+		parts = append(parts, ai.NewToolRequestPart(&ai.ToolRequest{
+			Name:  call.Function.Name,
+			Input: call.Function.Arguments,
+		}))
+	}
+	return parts
+}
+
+// chatRole maps Genkit roles to Ollama roles
+func chatRole(role ai.Role) string {
+	switch role {
+	case ai.RoleModel:
+		return "assistant"
+	case ai.RoleSystem:
+		return "system"
+	case ai.RoleTool:
+		return "tool"
+	default:
+		return "user"
+	}
+}
+
+// mediaData returns the base64 payload of a data URI
+func mediaData(uri string) (string, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return "", fmt.Errorf("unsupported media URL, only data URIs are supported")
+	}
+	_, data, ok := strings.Cut(uri, ";base64,")
+	if !ok {
+		return "", fmt.Errorf("media data URI is not base64 encoded")
+	}
+	return data, nil
+}
+
+// optionNames maps Genkit config names, as used in dotprompt frontmatter,
+// to Ollama option names
+var optionNames = map[string]string{
+	"maxOutputTokens": "num_predict",
+	"topK":            "top_k",
+	"topP":            "top_p",
+	"stopSequences":   "stop",
+}
+
+// buildOptions converts the Genkit generation config to Ollama model options.
+// Maps use Ollama option names (e.g. "temperature", "num_ctx"); Genkit names
+// of the common options are translated.
+func buildOptions(config any) (map[string]any, error) {
+	switch c := config.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		options := make(map[string]any, len(c))
+		for k, v := range c {
+			if name, ok := optionNames[k]; ok {
+				k = name
+			}
+			options[k] = v
+		}
+		return options, nil
+	case *ai.GenerationCommonConfig:
+		if c == nil {
+			return nil, nil
+		}
+		return commonOptions(*c), nil
+	case ai.GenerationCommonConfig:
+		return commonOptions(c), nil
+	default:
+		return nil, fmt.Errorf("unsupported generation config type %T", config)
+	}
+}
+
+// commonOptions converts Genkit's common config to Ollama model options
+func commonOptions(c ai.GenerationCommonConfig) map[string]any {
+	options := make(map[string]any)
+	if c.Temperature != 0 {
+		options["temperature"] = c.Temperature
+	}
+	if c.TopK != 0 {
+		options["top_k"] = c.TopK
+	}
+	if c.TopP != 0 {
+		options["top_p"] = c.TopP
+	}
+	if c.MaxOutputTokens != 0 {
+		options["num_predict"] = c.MaxOutputTokens
+	}
+	if len(c.StopSequences) > 0 {
+		options["stop"] = c.StopSequences
+	}
+	return options
+}
+
+// apiError builds a classified error from a non-200 Ollama API response
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	err := fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		err = fmt.Errorf("ollama API returned status %d: %s", resp.StatusCode, errResp.Error)
+	}
+	return &Error{
+		Kind:       classifyStatus(resp.StatusCode, errResp.Error),
+		StatusCode: resp.StatusCode,
+		Err:        err,
+	}
+}
@@ -0,0 +1,43 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tchat/internal/httpclient"
+)
+
+// DefaultHostName is the name of the Ollama host when none are configured,
+// so its models are registered as "ollama/<model>"
+const DefaultHostName = "ollama"
+
+// SplitModel splits a model identifier into the host name and the model
+// name known to Ollama, e.g. "laptop/llama3.2:3b" into "laptop" and "llama3.2:3b"
+func SplitModel(model string) (host, name string) {
+	host, name, ok := strings.Cut(model, "/")
+	if !ok {
+		return DefaultHostName, model
+	}
+	return host, name
+}
+
+// CheckHealth reports whether an Ollama server answers (/api/version)
+func CheckHealth(ctx context.Context, serverAddress string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, serverAddress+"/api/version", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpclient.Default().Do(httpReq)
+	if err != nil {
+		return classifyRequestError(fmt.Errorf("failed to reach Ollama: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package ollama
+
+import "testing"
+
+func TestSplitModel(t *testing.T) {
+	tests := []struct {
+		model, host, name string
+	}{
+		{"laptop/llama3.2:3b", "laptop", "llama3.2:3b"},
+		{"llama3.2:3b", DefaultHostName, "llama3.2:3b"},
+		{"server/hf.co/user/model:Q4_K_M", "server", "hf.co/user/model:Q4_K_M"},
+	}
+	for _, tt := range tests {
+		host, name := SplitModel(tt.model)
+		if host != tt.host || name != tt.name {
+			t.Errorf("SplitModel(%q) = %q, %q; want %q, %q", tt.model, host, name, tt.host, tt.name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"tchat/internal/httpclient"
+)
+
+var (
+	keepAliveMu      sync.RWMutex
+	defaultKeepAlive string
+	keepAlive        = make(map[string]string) // model name, with or without host prefix -> keep_alive
+)
+
+// SetKeepAlive sets how long Ollama keeps models in memory after a request,
+// e.g. "10m", "1h", "-1" to keep them loaded or "0" to unload right away.
+// perModel is keyed by model identifier, or by model name for the model on
+// any host. An empty value uses Ollama's default of five minutes.
+func SetKeepAlive(defaultValue string, perModel map[string]string) {
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+
+	defaultKeepAlive = defaultValue
+	keepAlive = make(map[string]string, len(perModel))
+	for model, value := range perModel {
+		keepAlive[model] = value
+	}
+}
+
+// KeepAlive returns the keep_alive setting for a model identifier, empty
+// for Ollama's default
+func KeepAlive(model string) string {
+	keepAliveMu.RLock()
+	defer keepAliveMu.RUnlock()
+	if value, ok := keepAlive[model]; ok {
+		return value
+	}
+	if _, name := SplitModel(model); name != model {
+		if value, ok := keepAlive[name]; ok {
+			return value
+		}
+	}
+	return defaultKeepAlive
+}
+
+// keepAliveValue converts a keep_alive setting to its JSON value.
+// Plain numbers are seconds, other values are durations such as "10m".
+func keepAliveValue(value string) any {
+	if value == "" {
+		return nil
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n
+	}
+	return value
+}
+
+// Preload loads a model into memory with its keep_alive setting,
+// so the first prompt does not wait for it
+func Preload(ctx context.Context, serverAddress, model string) error {
+	return loadRequest(ctx, serverAddress, model, keepAliveValue(KeepAlive(model)))
+}
+
+// Unload removes a model from memory
+func Unload(ctx context.Context, serverAddress, model string) error {
+	return loadRequest(ctx, serverAddress, model, 0)
+}
+
+// loadRequest sends a generate request without a prompt, which only
+// loads or unloads the model (/api/generate)
+func loadRequest(ctx context.Context, serverAddress, model string, keepAlive any) error {
+	_, name := SplitModel(model)
+	payload, err := json.Marshal(struct {
+		Model     string `json:"model"`
+		KeepAlive any    `json:"keep_alive,omitempty"`
+	}{
+		Model:     name,
+		KeepAlive: keepAlive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverAddress+"/api/generate", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.Streaming().Do(httpReq)
+	if err != nil {
+		return classifyRequestError(fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// RunningModel is a model loaded in memory, as listed by /api/ps
+type RunningModel struct {
+	Name          string    `json:"name"`
+	Model         string    `json:"model"`
+	Size          int64     `json:"size"`
+	SizeVRAM      int64     `json:"size_vram"`
+	Digest        string    `json:"digest"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	ContextLength int       `json:"context_length"`
+	Details       struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// ListRunning lists the models loaded in memory (/api/ps)
+func ListRunning(ctx context.Context, serverAddress string) ([]RunningModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, serverAddress+"/api/ps", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpclient.Default().Do(httpReq)
+	if err != nil {
+		return nil, classifyRequestError(fmt.Errorf("failed to list running models: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp)
+	}
+
+	var psResp struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&psResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return psResp.Models, nil
+}
@@ -2,16 +2,27 @@ package ollama
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"slices"
+	"sync"
 	"time"
 
+	"tchat/internal/httpclient"
+
 	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
 	"github.com/firebase/genkit/go/genkit"
-	"github.com/firebase/genkit/go/plugins/ollama"
+)
+
+// capabilities caches the /api/show capabilities of registered models,
+// keyed by the model identifier with host prefix
+var (
+	capabilitiesMu sync.RWMutex
+	capabilities   = make(map[string][]string)
 )
 
 // ListModelsResponse represents the response from the List Local Models API (/api/tags)
@@ -38,10 +49,12 @@ type FetchModelDetailsRequest struct {
 
 // FetchModelDetailsResponse represents the response from the fetch Model Details API (/api/show)
 type FetchModelDetailsResponse struct {
-	ModifiedAt   time.Time `json:"modified_at"`
-	Template     string    `json:"template"`
-	Parameters   string    `json:"parameters"`
-	Capabilities []string  `json:"capabilities"`
+	ModifiedAt   time.Time      `json:"modified_at"`
+	Template     string         `json:"template"`
+	Parameters   string         `json:"parameters"`
+	Capabilities []string       `json:"capabilities"`
+	License      string         `json:"license"`
+	ModelInfo    map[string]any `json:"model_info"`
 	Details      struct {
 		ParentModel       string   `json:"parent_model"`
 		Format            string   `json:"format"`
@@ -53,15 +66,20 @@ type FetchModelDetailsResponse struct {
 }
 
 // ListModels lists available models from Ollama API endpoint /api/tags
-func ListModels(serverAddress string) ([]string, error) {
-	resp, err := http.Get(serverAddress + "/api/tags")
+func ListModels(ctx context.Context, serverAddress string) (*ListModelsResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, serverAddress+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpclient.Default().Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list models: %w", err)
+		return nil, classifyRequestError(fmt.Errorf("failed to list models: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+		return nil, apiError(resp)
 	}
 
 	var listResp ListModelsResponse
@@ -72,17 +90,12 @@ func ListModels(serverAddress string) ([]string, error) {
 	for _, m := range listResp.Models {
 		slog.Info("ListModels", slog.Any(m.Name, m))
 	}
-	models := make([]string, 0, len(listResp.Models))
-	for _, model := range listResp.Models {
-		models = append(models, model.Name)
-	}
-
-	return models, nil
+	return &listResp, nil
 }
 
 // FetchModelDetals fetches detailed information about a specific model
 // ollama endpoint: /api/show
-func FetchModelDetals(serverAddress, modelName string) (*FetchModelDetailsResponse, error) {
+func FetchModelDetals(ctx context.Context, serverAddress, modelName string) (*FetchModelDetailsResponse, error) {
 	reqBody := FetchModelDetailsRequest{
 		Model:   modelName,
 		Verbose: false,
@@ -93,7 +106,13 @@ func FetchModelDetals(serverAddress, modelName string) (*FetchModelDetailsRespon
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(serverAddress+"/api/show", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverAddress+"/api/show", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.Default().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch model details: %w", err)
 	}
@@ -112,6 +131,16 @@ func FetchModelDetals(serverAddress, modelName string) (*FetchModelDetailsRespon
 	return &modelDetails, nil
 }
 
+// ContextLength returns the maximum context length the model was trained
+// with, 0 if unknown. It is reported in model_info as "<architecture>.context_length".
+func (d *FetchModelDetailsResponse) ContextLength() int {
+	arch, _ := d.ModelInfo["general.architecture"].(string)
+	if n, ok := d.ModelInfo[arch+".context_length"].(float64); ok {
+		return int(n)
+	}
+	return 0
+}
+
 // BuildModelOptions converts model capabilities to genkit's ai.ModelOptions
 func BuildModelOptions(modelName string, capabilities []string) *ai.ModelOptions {
 	modelOpts := &ai.ModelOptions{
@@ -126,48 +155,91 @@ func BuildModelOptions(modelName string, capabilities []string) *ai.ModelOptions
 	return modelOpts
 }
 
-// RegisterModels lists and registers all available Ollama models with Genkit
-// Returns a list of model identifiers with "ollama/" prefix
-func RegisterModels(g *genkit.Genkit, ollamaObj *ollama.Ollama, serverAddress string) ([]string, error) {
-	slog.Info("Listing available Ollama models...")
-
-	modelNames, err := ListModels(serverAddress)
+// fetchCapabilities queries the capabilities of a model from /api/show
+func fetchCapabilities(ctx context.Context, serverAddress, modelName string) ([]string, error) {
+	modelDetails, err := FetchModelDetals(ctx, serverAddress, modelName)
 	if err != nil {
-		slog.Warn("Failed to list Ollama models", "error", err)
 		return nil, err
 	}
-	slog.Info("Available Ollama models", "count", len(modelNames), "models", modelNames)
-
-	// Define all Ollama models with Genkit
-	registeredModels := make([]string, 0, len(modelNames))
-	for i, modelName := range modelNames {
-		// Fetch model capabilities by querying ollama endpoint /api/show
-		fmt.Printf("  • Fetching details for %s (%d/%d)...\n", modelName, i+1, len(modelNames))
-		var modelOpts *ai.ModelOptions
-		modelDetails, err := FetchModelDetals(serverAddress, modelName)
-		if err != nil {
-			slog.Warn("Failed to fetch model capabilities, using defaults",
-				"model", modelName,
-				"error", err,
-			)
-		} else {
-			slog.Info("Model capabilities",
-				"model", modelName,
-				"capabilities", modelDetails.Capabilities,
-			)
-			modelOpts = BuildModelOptions(modelName, modelDetails.Capabilities)
+	slog.Info("Model capabilities",
+		"model", modelName,
+		"capabilities", modelDetails.Capabilities,
+	)
+	return modelDetails.Capabilities, nil
+}
+
+// registerModel defines a model of the server with Genkit. Nil capabilities
+// mean they are unknown and defaults are used. Returns the model identifier
+// with the host prefix.
+func (p *Provider) registerModel(g *genkit.Genkit, modelName string, caps []string) string {
+	id := p.name + "/" + modelName
+	if caps != nil {
+		setCapabilities(id, caps)
+	}
+	modelOpts := BuildModelOptions(id, caps)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Genkit cannot define a model twice. A model whose capabilities were
+	// unknown, or changed by /create or /pull, keeps its definition with
+	// updated capabilities.
+	if defined, ok := p.models[id]; ok {
+		if caps != nil {
+			updateSupports(g, id, defined, modelOpts.Supports)
 		}
+		slog.Info("Ollama model already registered", "name", id)
+		return id
+	}
+	if genkit.LookupModel(g, id) != nil {
+		slog.Info("Ollama model already registered", "name", id)
+		return id
+	}
+
+	// Define model with tchat's chat generator so generation config is honored
+	model := p.defineModel(g, modelName, chatTimeout, modelOpts)
+	p.models[id] = modelOpts
 
-		// Define model with options when available
-		model := ollamaObj.DefineModel(g, ollama.ModelDefinition{
-			Name: modelName,
-			Type: "chat",
-		}, modelOpts)
+	slog.Info("Registered Ollama model", "name", model.Name(), "address", p.address)
+	return id
+}
 
-		slog.Info("Registered Ollama model", "name", model.Name())
+// updateSupports replaces what a defined model supports. Genkit checks
+// requests against the options the model was defined with, and reports
+// them in the model's metadata, so both are updated in place.
+func updateSupports(g *genkit.Genkit, id string, opts *ai.ModelOptions, supports *ai.ModelSupports) {
+	if opts.Supports.Media == supports.Media && opts.Supports.Tools == supports.Tools {
+		return
+	}
+	opts.Supports.Media, opts.Supports.Tools = supports.Media, supports.Tools
 
-		registeredModels = append(registeredModels, "ollama/"+modelName)
+	m, ok := genkit.LookupModel(g, id).(interface{ Desc() api.ActionDesc })
+	if !ok {
+		return
 	}
+	modelMeta, _ := m.Desc().Metadata["model"].(map[string]any)
+	if meta, ok := modelMeta["supports"].(map[string]any); ok {
+		meta["media"] = supports.Media
+		meta["tools"] = supports.Tools
+	}
+	slog.Info("Updated Ollama model capabilities", "name", id, "media", supports.Media, "tools", supports.Tools)
+}
+
+// setCapabilities records the capabilities of a registered model
+func setCapabilities(model string, caps []string) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities[model] = slices.Clone(caps)
+}
+
+// Capabilities returns the capabilities reported by Ollama for a registered model
+func Capabilities(model string) []string {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	return slices.Clone(capabilities[model])
+}
 
-	return registeredModels, nil
+// SupportsTools reports whether a registered model advertises the tools capability
+func SupportsTools(model string) bool {
+	return slices.Contains(Capabilities(model), "tools")
 }
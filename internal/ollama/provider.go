@@ -0,0 +1,145 @@
+package ollama
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"tchat/internal/provider"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// maxDetailFetches bounds the concurrent /api/show requests during discovery
+const maxDetailFetches = 4
+
+// Provider lists and defines the models of an Ollama server
+type Provider struct {
+	name      string
+	address   string
+	cache     *ModelCache
+	transport http.RoundTripper // of the chat clients; nil for Go's default
+
+	mu     sync.Mutex
+	models map[string]*ai.ModelOptions // defined models, by identifier
+}
+
+// Option configures a Provider
+type Option func(*Provider)
+
+// WithModelCache caches the capabilities of the server's models, so
+// unchanged models are not queried again
+func WithModelCache(cache *ModelCache) Option {
+	return func(p *Provider) {
+		p.cache = cache
+	}
+}
+
+// WithTransport sets the transport of the chat requests, which have their
+// own timeout
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Provider) {
+		p.transport = transport
+	}
+}
+
+// New creates a provider for the Ollama server at address whose models are
+// named "<name>/<model>"
+func New(name, address string, opts ...Option) *Provider {
+	p := &Provider{name: name, address: address, models: make(map[string]*ai.ModelOptions)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.cache == nil {
+		p.cache = LoadModelCache("")
+	}
+	return p
+}
+
+// Name returns the prefix of the provider's models
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Address returns the address of the server
+func (p *Provider) Address() string {
+	return p.address
+}
+
+// CheckHealth reports whether the server answers
+func (p *Provider) CheckHealth(ctx context.Context) error {
+	return CheckHealth(ctx, p.address)
+}
+
+// Register lists the models of the server, fetches their details and
+// registers them as chat models or embedders. Details are fetched
+// concurrently, except for models whose digest is cached; failed fetches
+// are retried on the next call.
+func (p *Provider) Register(ctx context.Context, g *genkit.Genkit) (provider.Models, error) {
+	list, err := ListModels(ctx, p.address)
+	if err != nil {
+		return provider.Models{}, err
+	}
+	slog.Info("Available Ollama models", "host", p.name, "count", len(list.Models))
+
+	caps := make([][]string, len(list.Models))
+	digests := make([]string, 0, len(list.Models))
+	cached := 0
+	sem := make(chan struct{}, maxDetailFetches)
+	var wg sync.WaitGroup
+	for i, m := range list.Models {
+		digests = append(digests, m.Digest)
+		if cc, ok := p.cache.get(m.Digest); ok {
+			caps[i] = cc
+			cached++
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cc, err := fetchCapabilities(ctx, p.address, m.Name)
+			if err != nil {
+				slog.Warn("Failed to fetch model capabilities, using defaults", "host", p.name, "model", m.Name, "error", err)
+				return
+			}
+			caps[i] = cc
+			p.cache.put(p.name, m.Digest, m.Name, cc)
+		}()
+	}
+	wg.Wait()
+	slog.Info("Fetched model details", "host", p.name, "models", len(list.Models), "cached", cached)
+	if err := p.cache.retain(p.name, digests); err != nil {
+		slog.Warn("Failed to save model cache", "error", err)
+	}
+
+	// Genkit registration is done in order, so the model list is stable
+	models := provider.Models{Chat: make([]string, 0, len(list.Models))}
+	for i, m := range list.Models {
+		if IsEmbedding(caps[i]) {
+			models.Embedders = append(models.Embedders, p.registerEmbedder(g, m.Name, caps[i]))
+			continue
+		}
+		models.Chat = append(models.Chat, p.registerModel(g, m.Name, caps[i]))
+	}
+	return models, nil
+}
+
+// RegisterModel fetches the details of one model of the server, e.g. after
+// pulling it, and defines it with Genkit. Returns the model identifier and
+// whether it is an embedding model.
+func (p *Provider) RegisterModel(ctx context.Context, g *genkit.Genkit, modelName string) (string, bool) {
+	caps, err := fetchCapabilities(ctx, p.address, modelName)
+	if err != nil {
+		slog.Warn("Failed to fetch model capabilities, using defaults", "host", p.name, "model", modelName, "error", err)
+	}
+	if IsEmbedding(caps) {
+		return p.registerEmbedder(g, modelName, caps), true
+	}
+	return p.registerModel(g, modelName, caps), false
+}
@@ -0,0 +1,68 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func TestRegisterUpdatesCapabilities(t *testing.T) {
+	caps := "" // /api/show fails while empty
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llava:7b","digest":"d1"}]}`)
+	})
+	mux.HandleFunc("POST /api/show", func(w http.ResponseWriter, r *http.Request) {
+		if caps == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"capabilities":[%s]}`, caps)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	g := genkit.Init(context.Background())
+	p := New("lm", server.URL, WithModelCache(LoadModelCache(filepath.Join(t.TempDir(), "cache.json"))))
+	supports := func() (media, tools bool) {
+		m := genkit.LookupModel(g, "lm/llava:7b").(interface{ Desc() api.ActionDesc })
+		modelMeta := m.Desc().Metadata["model"].(map[string]any)
+		s := modelMeta["supports"].(map[string]any)
+		return s["media"].(bool), s["tools"].(bool)
+	}
+
+	// The details cannot be fetched; the model is defined with defaults
+	if _, err := p.Register(context.Background(), g); err != nil {
+		t.Fatal(err)
+	}
+	if media, tools := supports(); media || tools {
+		t.Errorf("model without known capabilities supports media %v, tools %v", media, tools)
+	}
+
+	// The next registration fetches them again and updates the definition
+	caps = `"completion","vision","tools"`
+	if _, err := p.Register(context.Background(), g); err != nil {
+		t.Fatal(err)
+	}
+	if media, tools := supports(); !media || !tools {
+		t.Errorf("after refetching, model supports media %v, tools %v", media, tools)
+	}
+	if opts := p.models["lm/llava:7b"]; !opts.Supports.Media || !opts.Supports.Tools {
+		t.Errorf("the options Genkit checks requests against were not updated: %+v", opts.Supports)
+	}
+
+	// A model replaced by /create loses a capability
+	caps = `"completion","vision"`
+	if model, _ := p.RegisterModel(context.Background(), g, "llava:7b"); model != "lm/llava:7b" {
+		t.Errorf("RegisterModel() = %q", model)
+	}
+	if media, tools := supports(); !media || tools {
+		t.Errorf("after /create, model supports media %v, tools %v", media, tools)
+	}
+}
@@ -0,0 +1,99 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"tchat/internal/httpclient"
+)
+
+// PullProgress is one status update of the Pull API (/api/pull)
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PullCallback receives the progress of a pull
+type PullCallback func(PullProgress)
+
+// ModelName normalizes a model name as listed by /api/tags: without the
+// "ollama/" prefix and with the ":latest" tag if no tag is given
+func ModelName(name string) string {
+	name = strings.TrimPrefix(name, DefaultHostName+"/")
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	return name
+}
+
+// Pull downloads a model from the Ollama library, reporting progress to
+// onProgress until the model is pulled or ctx is cancelled
+func Pull(ctx context.Context, serverAddress, model string, onProgress PullCallback) error {
+	payload, err := json.Marshal(struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}{
+		Model:  ModelName(model),
+		Stream: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverAddress+"/api/pull", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.Streaming().Do(httpReq)
+	if err != nil {
+		return classifyRequestError(fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	return readProgress(resp.Body, "pull", onProgress)
+}
+
+// readProgress reads the status updates Ollama streams while pulling or
+// creating a model, one JSON object per line, until "success"
+func readProgress(body io.Reader, op string, onProgress PullCallback) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var progress PullProgress
+		if err := json.Unmarshal(line, &progress); err != nil {
+			return fmt.Errorf("failed to decode %s progress: %w", op, err)
+		}
+		if progress.Error != "" {
+			return classifyMessage(progress.Error)
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+		if progress.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return classifyRequestError(fmt.Errorf("reading %s progress: %w", op, err))
+	}
+	return fmt.Errorf("%s ended without success", op)
+}
@@ -0,0 +1,19 @@
+package ollama
+
+import "testing"
+
+func TestModelName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"llama3.2", "llama3.2:latest"},
+		{"llama3.2:3b", "llama3.2:3b"},
+		{"ollama/qwen3", "qwen3:latest"},
+		{"hf.co/user/model:Q4_K_M", "hf.co/user/model:Q4_K_M"},
+	}
+	for _, tt := range tests {
+		if got := ModelName(tt.name); got != tt.want {
+			t.Errorf("ModelName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
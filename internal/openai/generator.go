@@ -0,0 +1,346 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// chatMessage is a message of the chat completions API. Content is a
+// string, or a list of parts for messages with images.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// contentPart is a text or image part of a user message
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// chatRequest is a request of the chat completions API (/v1/chat/completions)
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	TopK        *int          `json:"top_k,omitempty"`
+	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	Seed        *int          `json:"seed,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+	// ResponseFormat constrains the output to JSON, optionally matching a schema
+	ResponseFormat map[string]any `json:"response_format,omitempty"`
+}
+
+// chatResponse is a response, or a streamed chunk, of the chat completions API
+type chatResponse struct {
+	Choices []struct {
+		Message      delta  `json:"message"`
+		Delta        delta  `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// delta is the message, or the streamed part of it, of a choice.
+// Reasoning models served by llama.cpp or vLLM send reasoning_content.
+type delta struct {
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content"`
+}
+
+// chatGenerator generates responses for one model with the chat completions API
+type chatGenerator struct {
+	provider *Provider
+	model    string
+}
+
+// generate implements ai.ModelFunc
+func (cg *chatGenerator) generate(ctx context.Context, input *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+	req := chatRequest{
+		Model:  cg.model,
+		Stream: cb != nil,
+	}
+	for _, m := range input.Messages {
+		req.Messages = append(req.Messages, toChatMessage(m))
+	}
+	if err := applyConfig(&req, input.Config); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cg.provider.baseURL+"/v1/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := cg.provider.do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("chat request to %s failed: %w", cg.provider.name, err)
+	}
+	defer resp.Body.Close()
+
+	response := &ai.ModelResponse{
+		Request: input,
+		Message: &ai.Message{Role: ai.RoleModel},
+	}
+
+	if cb == nil {
+		var chatResp chatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if len(chatResp.Choices) > 0 {
+			response.Message.Content = toParts(chatResp.Choices[0].Message)
+		}
+		finish(response, chatResp)
+		return response, nil
+	}
+
+	// Streaming: server-sent events with one chunk per "data:" line
+	var reasoning, text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunkResp chatResponse
+		if err := json.Unmarshal([]byte(data), &chunkResp); err != nil {
+			return nil, fmt.Errorf("failed to decode chunk: %w", err)
+		}
+		if len(chunkResp.Choices) > 0 {
+			d := chunkResp.Choices[0].Delta
+			reasoning.WriteString(d.ReasoningContent)
+			text.WriteString(d.Content)
+			if parts := toParts(d); len(parts) > 0 {
+				if err := cb(ctx, &ai.ModelResponseChunk{Role: ai.RoleModel, Content: parts}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		finish(response, chunkResp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading response stream: %w", err)
+	}
+
+	response.Message.Content = toParts(delta{Content: text.String(), ReasoningContent: reasoning.String()})
+	if response.FinishReason == "" {
+		response.FinishReason = ai.FinishReasonUnknown
+	}
+	return response, nil
+}
+
+// finish copies the finish reason and token usage, as far as the response has them
+func finish(response *ai.ModelResponse, chatResp chatResponse) {
+	if len(chatResp.Choices) > 0 {
+		switch chatResp.Choices[0].FinishReason {
+		case "":
+		case "length":
+			response.FinishReason = ai.FinishReasonLength
+		default:
+			response.FinishReason = ai.FinishReasonStop
+		}
+	}
+	if u := chatResp.Usage; u != nil {
+		response.Usage = &ai.GenerationUsage{
+			InputTokens:  u.PromptTokens,
+			OutputTokens: u.CompletionTokens,
+			TotalTokens:  u.PromptTokens + u.CompletionTokens,
+		}
+	}
+}
+
+// toChatMessage converts a Genkit message. Reasoning and tool parts are
+// not sent; images are sent as data URLs.
+func toChatMessage(m *ai.Message) chatMessage {
+	var text strings.Builder
+	var images []string
+	for _, part := range m.Content {
+		switch {
+		case part.IsText():
+			text.WriteString(part.Text)
+		case part.IsMedia():
+			images = append(images, part.Text)
+		}
+	}
+
+	msg := chatMessage{Role: chatRole(m.Role), Content: text.String()}
+	if len(images) > 0 {
+		parts := []contentPart{{Type: "text", Text: text.String()}}
+		for _, url := range images {
+			p := contentPart{Type: "image_url"}
+			p.ImageURL = &struct {
+				URL string `json:"url"`
+			}{URL: url}
+			parts = append(parts, p)
+		}
+		msg.Content = parts
+	}
+	return msg
+}
+
+// toParts converts a message, or a streamed part of it, to Genkit parts
+func toParts(d delta) []*ai.Part {
+	var parts []*ai.Part
+	if d.ReasoningContent != "" {
+		parts = append(parts, ai.NewReasoningPart(d.ReasoningContent, nil))
+	}
+	if d.Content != "" {
+		parts = append(parts, ai.NewTextPart(d.Content))
+	}
+	return parts
+}
+
+// chatRole maps Genkit roles to chat completions roles
+func chatRole(role ai.Role) string {
+	switch role {
+	case ai.RoleModel:
+		return "assistant"
+	case ai.RoleSystem:
+		return "system"
+	default:
+		return "user"
+	}
+}
+
+// applyConfig sets the sampling parameters of the generation config. Maps
+// use Ollama option names, as set with /param, or Genkit names; options
+// the API does not know, such as num_ctx, are left out.
+func applyConfig(req *chatRequest, config any) error {
+	switch c := config.(type) {
+	case nil:
+		return nil
+	case *ai.GenerationCommonConfig:
+		if c != nil {
+			applyCommon(req, *c)
+		}
+		return nil
+	case ai.GenerationCommonConfig:
+		applyCommon(req, c)
+		return nil
+	case map[string]any:
+		for name, value := range c {
+			switch name {
+			case "temperature":
+				req.Temperature = floatValue(value)
+			case "top_p", "topP":
+				req.TopP = floatValue(value)
+			case "top_k", "topK":
+				req.TopK = intValue(value)
+			case "num_predict", "maxOutputTokens":
+				req.MaxTokens = intValue(value)
+			case "seed":
+				req.Seed = intValue(value)
+			case "stop", "stopSequences":
+				req.Stop = stringsValue(value)
+			case "format":
+				req.ResponseFormat = responseFormat(value)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported generation config type %T", config)
+	}
+}
+
+// applyCommon sets the parameters of Genkit's common config
+func applyCommon(req *chatRequest, c ai.GenerationCommonConfig) {
+	if c.Temperature != 0 {
+		req.Temperature = &c.Temperature
+	}
+	if c.TopP != 0 {
+		req.TopP = &c.TopP
+	}
+	if c.TopK != 0 {
+		req.TopK = &c.TopK
+	}
+	if c.MaxOutputTokens != 0 {
+		req.MaxTokens = &c.MaxOutputTokens
+	}
+	req.Stop = c.StopSequences
+}
+
+// responseFormat converts the Ollama format option, "json" or a JSON Schema
+func responseFormat(v any) map[string]any {
+	if schema, ok := v.(map[string]any); ok {
+		return map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "output", "schema": schema},
+		}
+	}
+	return map[string]any{"type": "json_object"}
+}
+
+// floatValue converts a numeric config value, nil if it is not a number
+func floatValue(v any) *float64 {
+	var f float64
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case int:
+		f = float64(n)
+	default:
+		return nil
+	}
+	return &f
+}
+
+// intValue converts a numeric config value, nil if it is not a number
+func intValue(v any) *int {
+	var i int
+	switch n := v.(type) {
+	case int:
+		i = n
+	case float64:
+		i = int(n)
+	default:
+		return nil
+	}
+	return &i
+}
+
+// stringsValue converts a stop sequence config value
+func stringsValue(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []any:
+		var stops []string
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				stops = append(stops, str)
+			}
+		}
+		return stops
+	default:
+		return nil
+	}
+}
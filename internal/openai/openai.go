@@ -0,0 +1,198 @@
+// Package openai serves models of servers with an OpenAI-compatible API,
+// such as llama.cpp server, LM Studio or vLLM
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"tchat/internal/provider"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// DefaultTimeout limits how long a chat request may take
+const DefaultTimeout = 5 * time.Minute
+
+// Provider lists and defines the models of an OpenAI-compatible server
+type Provider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// Option configures a Provider
+type Option func(*Provider)
+
+// WithAPIKey sends the key as bearer token with every request
+func WithAPIKey(key string) Option {
+	return func(p *Provider) {
+		p.apiKey = key
+	}
+}
+
+// WithHTTPClient sets the client used for all requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// New creates a provider whose models are named "<name>/<model>". The base
+// URL may end in /v1, e.g. "http://localhost:1234/v1".
+func New(name, baseURL string, opts ...Option) *Provider {
+	p := &Provider{
+		name:    name,
+		baseURL: strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1"),
+		client:  &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Name returns the prefix of the provider's models
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Address returns the base URL of the server
+func (p *Provider) Address() string {
+	return p.baseURL
+}
+
+// ListModels lists the models of the server (/v1/models)
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var listResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]string, 0, len(listResp.Data))
+	for _, m := range listResp.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// CheckHealth reports whether the server answers by listing its models
+func (p *Provider) CheckHealth(ctx context.Context) error {
+	_, err := p.ListModels(ctx)
+	return err
+}
+
+// Register lists the models of the server and defines the ones not yet
+// known with Genkit
+func (p *Provider) Register(ctx context.Context, g *genkit.Genkit) (provider.Models, error) {
+	names, err := p.ListModels(ctx)
+	if err != nil {
+		return provider.Models{}, err
+	}
+	slog.Info("Available models", "host", p.name, "count", len(names))
+
+	models := provider.Models{Chat: make([]string, 0, len(names))}
+	for _, name := range names {
+		id := p.name + "/" + name
+		if genkit.LookupModel(g, id) == nil {
+			p.DefineModel(g, name)
+			slog.Info("Registered model", "name", id, "address", p.baseURL)
+		}
+		models.Chat = append(models.Chat, id)
+	}
+	return models, nil
+}
+
+// DefineModel defines a model of the server with Genkit. Tool calling and
+// images are not offered, since servers differ in their support.
+func (p *Provider) DefineModel(g *genkit.Genkit, model string) ai.Model {
+	gen := &chatGenerator{provider: p, model: model}
+	opts := &ai.ModelOptions{
+		Label: p.name + "/" + model,
+		Supports: &ai.ModelSupports{
+			Multiturn:  true,
+			SystemRole: true,
+		},
+	}
+	return genkit.DefineModel(g, api.NewName(p.name, model), opts, gen.generate)
+}
+
+// do sends a request with the API key and checks the response status
+func (p *Provider) do(httpReq *http.Request) (*http.Response, error) {
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp, nil
+}
+
+// requestError marks errors of requests that did not reach the server, so
+// chat can fail over. Timeouts and cancellations are returned as they are.
+func requestError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.Canceled) || errors.As(err, &netErr) && netErr.Timeout() {
+		return err
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return fmt.Errorf("%w: %w", provider.ErrUnreachable, err)
+	}
+	return err
+}
+
+// apiError builds an error from a non-200 response. Servers report errors
+// as {"error": {"message": ...}} or {"error": "..."}.
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var errResp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && len(errResp.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		var message string
+		if json.Unmarshal(errResp.Error, &detail) == nil && detail.Message != "" {
+			message = detail.Message
+		} else if json.Unmarshal(errResp.Error, &message) != nil {
+			message = string(errResp.Error)
+		}
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, message)
+	}
+	return fmt.Errorf("API returned status %d", resp.StatusCode)
+}
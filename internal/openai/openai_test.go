@@ -0,0 +1,189 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tchat/internal/provider"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// newServer starts a stand-in for an OpenAI-compatible server. The chat
+// handler receives the decoded request.
+func newServer(t *testing.T, chat func(w http.ResponseWriter, req chatRequest)) *Provider {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen3-8b"},{"id":"llama-3.2-3b"}]}`)
+	})
+	mux.HandleFunc("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding chat request: %v", err)
+		}
+		chat(w, req)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return New("lm", server.URL+"/v1", WithAPIKey("secret"), WithHTTPClient(server.Client()))
+}
+
+func TestListModels(t *testing.T) {
+	p := newServer(t, nil)
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(models, ",") != "qwen3-8b,llama-3.2-3b" {
+		t.Errorf("got models %v", models)
+	}
+
+	// Without the key the server answers {"error":{"message":...}}
+	p.apiKey = ""
+	if _, err := p.ListModels(context.Background()); err == nil || !strings.Contains(err.Error(), "status 401: invalid api key") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := newServer(t, nil)
+	g := genkit.Init(context.Background())
+	models, err := p.Register(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(models.Chat, ",") != "lm/qwen3-8b,lm/llama-3.2-3b" || len(models.Embedders) != 0 {
+		t.Errorf("got models %+v", models)
+	}
+	if genkit.LookupModel(g, "lm/qwen3-8b") == nil {
+		t.Errorf("model was not defined with Genkit")
+	}
+
+	// Registering again keeps the definitions
+	if _, err := p.Register(context.Background(), g); err != nil {
+		t.Errorf("registering again: %v", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var got chatRequest
+	p := newServer(t, func(w http.ResponseWriter, req chatRequest) {
+		got = req
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Hi!"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+	})
+	gen := &chatGenerator{provider: p, model: "qwen3-8b"}
+	resp, err := gen.generate(context.Background(), &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewSystemTextMessage("Be brief."), ai.NewUserTextMessage("Hello")},
+		Config:   map[string]any{"temperature": 0.2, "num_ctx": 8192, "format": "json"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Model != "qwen3-8b" || got.Stream || len(got.Messages) != 2 || got.Messages[0].Role != "system" {
+		t.Errorf("got request %+v", got)
+	}
+	if got.Temperature == nil || *got.Temperature != 0.2 || got.ResponseFormat["type"] != "json_object" {
+		t.Errorf("generation config was not applied: %+v", got)
+	}
+	if resp.Text() != "Hi!" || resp.FinishReason != ai.FinishReasonStop {
+		t.Errorf("got response %q, finish reason %q", resp.Text(), resp.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 15 {
+		t.Errorf("got usage %+v", resp.Usage)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	p := newServer(t, func(w http.ResponseWriter, req chatRequest) {
+		if !req.Stream {
+			t.Errorf("request is not streamed")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"choices":[{"delta":{"reasoning_content":"The user "}}]}`,
+			`{"choices":[{"delta":{"reasoning_content":"greets."}}]}`,
+			`{"choices":[{"delta":{"content":"Hello"}}]}`,
+			`{"choices":[{"delta":{"content":" there"},"finish_reason":"length"}]}`,
+			`[DONE]`,
+			`{"choices":[{"delta":{"content":" ignored"}}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	})
+	gen := &chatGenerator{provider: p, model: "qwen3-8b"}
+	var chunks []string
+	resp, err := gen.generate(context.Background(), &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Hello")},
+	}, func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+		for _, part := range chunk.Content {
+			chunks = append(chunks, part.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(chunks, "|") != "The user |greets.|Hello| there" {
+		t.Errorf("got chunks %q", chunks)
+	}
+	if resp.Reasoning() != "The user greets." || resp.Text() != "Hello there" {
+		t.Errorf("got reasoning %q, text %q", resp.Reasoning(), resp.Text())
+	}
+	if resp.FinishReason != ai.FinishReasonLength {
+		t.Errorf("got finish reason %q", resp.FinishReason)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"error object", `{"error":{"message":"model not loaded","type":"invalid_request_error"}}`, "model not loaded"},
+		{"error string", `{"error":"context size exceeded"}`, "context size exceeded"},
+		{"no error body", `Bad Gateway`, "API returned status 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newServer(t, func(w http.ResponseWriter, req chatRequest) {
+				w.WriteHeader(http.StatusBadGateway)
+				fmt.Fprint(w, tt.body)
+			})
+			gen := &chatGenerator{provider: p, model: "qwen3-8b"}
+			_, err := gen.generate(context.Background(), &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("Hello")},
+			}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	p := New("lm", server.URL+"/v1")
+	gen := &chatGenerator{provider: p, model: "qwen3-8b"}
+	_, err := gen.generate(context.Background(), &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Hello")},
+	}, nil)
+	if !errors.Is(err, provider.ErrUnreachable) {
+		t.Errorf("got error %v, want it to match provider.ErrUnreachable", err)
+	}
+}
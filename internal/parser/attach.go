@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Size limits of files attached with @path
+const (
+	MaxAttachmentSize  = 100 * 1024 // bytes of one attachment
+	MaxAttachmentTotal = 256 * 1024 // bytes of all attachments of one prompt
+)
+
+// lineRange matches a reference with a line range, e.g. "main.go:100-150" or "main.go:42"
+var lineRange = regexp.MustCompile(`^(.+):(\d+)(?:-(\d+))?$`)
+
+// languages maps file extensions to fenced code block languages where they differ
+var languages = map[string]string{
+	"py":  "python",
+	"js":  "javascript",
+	"ts":  "typescript",
+	"rs":  "rust",
+	"rb":  "ruby",
+	"sh":  "bash",
+	"yml": "yaml",
+	"md":  "markdown",
+	"h":   "c",
+	"hpp": "cpp",
+}
+
+// sourceExtensions are extensions of text files people attach. A reference
+// with one of them is meant as a file even if it does not exist.
+var sourceExtensions = map[string]bool{
+	"go": true, "py": true, "js": true, "ts": true, "tsx": true, "jsx": true,
+	"rs": true, "rb": true, "java": true, "kt": true, "swift": true,
+	"c": true, "h": true, "cpp": true, "hpp": true, "cs": true, "php": true,
+	"sh": true, "sql": true, "html": true, "css": true, "md": true, "txt": true,
+	"json": true, "yaml": true, "yml": true, "toml": true, "xml": true, "mod": true,
+}
+
+// Attachment is a text file referenced in user input as @path, or as
+// @path:start-end to attach only some lines
+type Attachment struct {
+	Path      string // as referenced, without @ and line range
+	StartLine int    // first attached line; 0 when the whole file is attached
+	EndLine   int    // last attached line; 0 when the whole file is attached
+	Content   string
+}
+
+// Label names the attachment, e.g. "main.go" or "main.go:100-150"
+func (a Attachment) Label() string {
+	switch a.StartLine {
+	case 0:
+		return a.Path
+	case a.EndLine:
+		return fmt.Sprintf("%s:%d", a.Path, a.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", a.Path, a.StartLine, a.EndLine)
+}
+
+// Lines returns the number of attached lines
+func (a Attachment) Lines() int {
+	return strings.Count(strings.TrimSuffix(a.Content, "\n"), "\n") + 1
+}
+
+// block renders the attachment as a labelled fenced code block
+func (a Attachment) block() string {
+	lang := extension(a.Path)
+	if l, ok := languages[lang]; ok {
+		lang = l
+	}
+	label := "File: " + a.Path
+	switch a.StartLine {
+	case 0:
+	case a.EndLine:
+		label += fmt.Sprintf(" (line %d)", a.StartLine)
+	default:
+		label += fmt.Sprintf(" (lines %d-%d)", a.StartLine, a.EndLine)
+	}
+	fence := fenceFor(a.Content)
+	return fmt.Sprintf("%s\n%s%s\n%s\n%s", label, fence, lang, strings.TrimSuffix(a.Content, "\n"), fence)
+}
+
+// Attach reads the files referenced with @path in the input and returns the
+// prompt with the references' @ removed and the files appended as labelled
+// fenced blocks. References to paths that do not exist, such as "@team" or
+// "@john.doe", are left alone; those that look like files, with a line
+// range or a source file extension, are returned as missing. Fails if an
+// existing file cannot be attached or the attachments exceed the size
+// limits.
+func Attach(input string) (prompt string, attachments []Attachment, missing []string, err error) {
+	seen := make(map[string]bool)
+	total := 0
+	prompt = input
+	for _, ref := range ParseLine(input).FileRefs {
+		a, err := readAttachment(ref)
+		if errors.Is(err, fs.ErrNotExist) {
+			if a.StartLine != 0 || sourceExtensions[extension(a.Path)] {
+				missing = append(missing, ref)
+			}
+			continue
+		}
+		if err != nil {
+			return "", nil, nil, err
+		}
+		prompt = strings.Replace(prompt, "@"+ref, ref, 1)
+		if seen[a.Label()] {
+			continue
+		}
+		seen[a.Label()] = true
+
+		total += len(a.Content)
+		if total > MaxAttachmentTotal {
+			return "", nil, nil, fmt.Errorf("attachments exceed the limit of %d KB in total; attach line ranges, e.g. @%s:1-100", MaxAttachmentTotal/1024, a.Path)
+		}
+		attachments = append(attachments, a)
+	}
+	if len(attachments) == 0 {
+		return input, nil, missing, nil
+	}
+
+	blocks := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		blocks = append(blocks, a.block())
+	}
+	return prompt + "\n\n" + strings.Join(blocks, "\n\n"), attachments, missing, nil
+}
+
+// readAttachment reads a referenced file or line range. The error wraps
+// fs.ErrNotExist if the path does not exist.
+func readAttachment(ref string) (Attachment, error) {
+	a := Attachment{Path: ref}
+	if m := lineRange.FindStringSubmatch(ref); m != nil && !fileExists(ref) {
+		a.Path = m[1]
+		a.StartLine, _ = strconv.Atoi(m[2])
+		a.EndLine = a.StartLine
+		if m[3] != "" {
+			a.EndLine, _ = strconv.Atoi(m[3])
+		}
+		if a.StartLine < 1 || a.EndLine < a.StartLine {
+			return a, fmt.Errorf("invalid line range in @%s", ref)
+		}
+	}
+
+	path := expandHome(a.Path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return a, fmt.Errorf("cannot attach @%s: %w", ref, err)
+	}
+	if info.IsDir() {
+		return a, fmt.Errorf("cannot attach @%s: it is a directory", ref)
+	}
+	if a.StartLine == 0 && info.Size() > MaxAttachmentSize {
+		return a, fmt.Errorf("cannot attach @%s: %d KB exceeds the limit of %d KB; attach a line range, e.g. @%s:1-100",
+			ref, info.Size()/1024, MaxAttachmentSize/1024, a.Path)
+	}
+
+	if a.StartLine == 0 {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return a, fmt.Errorf("cannot attach @%s: %w", ref, err)
+		}
+		a.Content = string(data)
+	} else {
+		a.Content, a.EndLine, err = readLines(path, a.StartLine, a.EndLine)
+		if err != nil {
+			return a, fmt.Errorf("cannot attach @%s: %w", ref, err)
+		}
+		if len(a.Content) > MaxAttachmentSize {
+			return a, fmt.Errorf("cannot attach @%s: %d KB exceeds the limit of %d KB", ref, len(a.Content)/1024, MaxAttachmentSize/1024)
+		}
+	}
+
+	if !utf8.ValidString(a.Content) || strings.ContainsRune(a.Content, 0) {
+		return a, fmt.Errorf("cannot attach @%s: it is not a text file", ref)
+	}
+	return a, nil
+}
+
+// readLines reads lines start to end of a file. An end past the last line
+// is reduced to it; returns the last line read.
+func readLines(path string, start, end int) (string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxAttachmentSize)
+	line := 0
+	for line < end && scanner.Scan() {
+		line++
+		if line >= start {
+			b.WriteString(scanner.Text())
+			b.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", 0, err
+	}
+	if line < start {
+		return "", 0, fmt.Errorf("the file has only %d lines", line)
+	}
+	return b.String(), line, nil
+}
+
+// fenceFor returns a code fence longer than any backtick run in the content
+func fenceFor(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// extension returns the lower case extension of a path without the dot
+func extension(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// fileExists reports whether a path names an existing file
+func fileExists(path string) bool {
+	_, err := os.Stat(expandHome(path))
+	return err == nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
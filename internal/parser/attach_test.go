@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile creates a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAttach(t *testing.T) {
+	path := writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+
+	prompt, attachments, missing, err := Attach("explain @" + path + " and @" + path + " to @team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].Lines() != 3 || len(missing) != 0 {
+		t.Fatalf("got attachments %+v, missing %v", attachments, missing)
+	}
+	if !strings.HasPrefix(prompt, "explain "+path+" and "+path+" to @team\n\nFile: "+path+"\n```go\npackage main") {
+		t.Errorf("got prompt %q", prompt)
+	}
+}
+
+func TestAttachLineRange(t *testing.T) {
+	path := writeFile(t, "notes.txt", "one\ntwo\nthree\nfour\n")
+
+	_, attachments, _, err := Attach("@" + path + ":2-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := attachments[0]
+	if a.Content != "two\nthree\n" || a.Label() != path+":2-3" {
+		t.Errorf("got %q labelled %q", a.Content, a.Label())
+	}
+
+	// An end past the last line is reduced to it
+	_, attachments, _, _ = Attach("@" + path + ":3-10")
+	if attachments[0].EndLine != 4 || attachments[0].Content != "three\nfour\n" {
+		t.Errorf("got lines %d-%d: %q", attachments[0].StartLine, attachments[0].EndLine, attachments[0].Content)
+	}
+
+	if _, _, _, err := Attach("@" + path + ":7-9"); err == nil || !strings.Contains(err.Error(), "only 4 lines") {
+		t.Errorf("got error %v for lines past the end", err)
+	}
+	if _, _, _, err := Attach("@" + path + ":3-2"); err == nil {
+		t.Errorf("reversed line range was accepted")
+	}
+}
+
+func TestAttachMissing(t *testing.T) {
+	input := "ask @john.doe about @golang/go, see @v1.2 and @does/not/exist.go or @gone.txt:1-5"
+	prompt, attachments, missing, err := Attach(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != input || len(attachments) != 0 {
+		t.Errorf("got prompt %q, attachments %+v", prompt, attachments)
+	}
+	if strings.Join(missing, ",") != "does/not/exist.go,gone.txt:1-5" {
+		t.Errorf("got missing %v", missing)
+	}
+}
+
+func TestAttachRejects(t *testing.T) {
+	dir := t.TempDir()
+	binary := writeFile(t, "image.bin", "\x00\x01\x02")
+	large := writeFile(t, "large.txt", strings.Repeat("x", MaxAttachmentSize+1))
+
+	for name, input := range map[string]string{
+		"directory":   "@" + dir,
+		"binary file": "@" + binary,
+		"large file":  "@" + large,
+	} {
+		if _, _, _, err := Attach(input); err == nil {
+			t.Errorf("%s was attached", name)
+		}
+	}
+}
+
+func TestFenceFor(t *testing.T) {
+	if got := fenceFor("no backticks"); got != "```" {
+		t.Errorf("got %q", got)
+	}
+	if got := fenceFor("a ```go\nblock\n``` and ````"); got != "`````" {
+		t.Errorf("got %q", got)
+	}
+}
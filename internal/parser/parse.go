@@ -10,6 +10,7 @@ type ParsedInput struct {
 	Mode       string
 	Text       string
 	ImagePaths []string
+	FileRefs   []string // @path references, without the @
 	Raw        string
 	Command    string
 	Args       []string
@@ -29,6 +30,9 @@ func ParseLine(line string) ParsedInput {
 	var imagePaths []string
 
 	for _, tok := range tokens {
+		if ref, ok := fileRef(tok); ok {
+			out.FileRefs = append(out.FileRefs, ref)
+		}
 		if isImageFile(tok) {
 			imagePaths = append(imagePaths, tok)
 		} else {
@@ -52,6 +56,16 @@ func ParseLine(line string) ParsedInput {
 	return out
 }
 
+// fileRef returns the path of an @path token, without trailing punctuation
+func fileRef(tok string) (string, bool) {
+	ref, ok := strings.CutPrefix(tok, "@")
+	if !ok {
+		return "", false
+	}
+	ref = strings.TrimSuffix(strings.TrimRight(ref, ".,!?;"), ":")
+	return ref, ref != ""
+}
+
 func isImageFile(path string) bool {
 	// remove trailing punctuation like ",", "?" etc
 	path = strings.TrimRight(path, ".,!?;:")
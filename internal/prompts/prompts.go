@@ -0,0 +1,313 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tchat/internal/flows"
+
+	"github.com/firebase/genkit/go/core/api"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/google/dotprompt/go/dotprompt"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Library holds the dotprompt files loaded from the prompts directory.
+// Prompts are registered with Genkit; they are rendered with dotprompt
+// directly because Genkit merges all rendered messages into one user message.
+type Library struct {
+	g       *genkit.Genkit
+	dir     string
+	names   []string
+	sources map[string]string // prompt name -> file content
+
+	mu sync.Mutex // dotprompt templates are not safe for concurrent use
+	dp *dotprompt.Dotprompt
+}
+
+// Info describes a loaded prompt
+type Info struct {
+	Name         string
+	Description  string
+	Model        string         // Model from the frontmatter, empty to use the current model
+	InputSchema  map[string]any // JSON Schema of the prompt variables
+	DefaultInput map[string]any // Default values of the prompt variables
+}
+
+// Load registers all .prompt files in dir with Genkit. Files starting with
+// "_" are registered as partials. The directory is created if it does not exist.
+func Load(g *genkit.Genkit, dir string) (*Library, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+
+	genkit.LoadPromptDir(g, dir, "")
+
+	l := &Library{
+		g:       g,
+		dir:     dir,
+		sources: make(map[string]string),
+	}
+	partials := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		filename := d.Name()
+		if d.IsDir() || !strings.HasSuffix(filename, ".prompt") {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			slog.Warn("Failed to read prompt file", "file", path, "error", err)
+			return nil
+		}
+
+		name := strings.TrimSuffix(filename, ".prompt")
+		if partial, ok := strings.CutPrefix(name, "_"); ok {
+			partials[partial] = string(source)
+			return nil
+		}
+		if genkit.LookupPrompt(g, name) == nil {
+			slog.Warn("Prompt file could not be loaded", "file", path)
+			return nil
+		}
+		l.names = append(l.names, name)
+		l.sources[name] = string(source)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+
+	l.dp = dotprompt.NewDotprompt(&dotprompt.DotpromptOptions{Partials: partials})
+	slices.Sort(l.names)
+	return l, nil
+}
+
+// Dir returns the directory prompts are loaded from
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// Names returns the names of all loaded prompts
+func (l *Library) Names() []string {
+	return slices.Clone(l.names)
+}
+
+// Info returns the description of a loaded prompt
+func (l *Library) Info(name string) (Info, bool) {
+	if !slices.Contains(l.names, name) {
+		return Info{}, false
+	}
+	p := genkit.LookupPrompt(l.g, name)
+	if p == nil {
+		return Info{}, false
+	}
+
+	info := Info{Name: name}
+	if d, ok := p.(interface{ Desc() api.ActionDesc }); ok {
+		desc := d.Desc()
+		info.InputSchema = desc.InputSchema
+		if meta, ok := desc.Metadata["prompt"].(map[string]any); ok {
+			info.Description, _ = meta["description"].(string)
+			info.Model, _ = meta["model"].(string)
+			info.DefaultInput, _ = meta["defaultInput"].(map[string]any)
+		}
+	}
+	return info, true
+}
+
+// Input builds the variables of a prompt from key=value arguments.
+// Values are converted to the types of the input schema and validated
+// against it; defaults from the frontmatter fill in missing variables.
+// Arguments without "=" continue the value of the previous argument,
+// so values may contain spaces.
+func (l *Library) Input(name string, args []string) (map[string]any, error) {
+	info, ok := l.Info(name)
+	if !ok {
+		return nil, fmt.Errorf("prompt not found: %s", name)
+	}
+
+	raw := make(map[string]string)
+	var last string
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			if last == "" {
+				return nil, fmt.Errorf("expected key=value, got %q", arg)
+			}
+			raw[last] += " " + arg
+			continue
+		}
+		raw[key] = value
+		last = key
+	}
+
+	input := maps.Clone(info.DefaultInput)
+	if input == nil {
+		input = make(map[string]any)
+	}
+	properties, _ := info.InputSchema["properties"].(map[string]any)
+	for key, value := range raw {
+		prop, known := properties[key].(map[string]any)
+		if properties != nil && !known {
+			return nil, fmt.Errorf("unknown variable %q, expected one of: %s", key, strings.Join(slices.Sorted(maps.Keys(properties)), ", "))
+		}
+		v, err := convert(value, schemaTypes(prop))
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", key, err)
+		}
+		input[key] = v
+	}
+
+	if info.InputSchema != nil {
+		result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(info.InputSchema), gojsonschema.NewGoLoader(input))
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate variables: %w", err)
+		}
+		if !result.Valid() {
+			msgs := make([]string, 0, len(result.Errors()))
+			for _, e := range result.Errors() {
+				msgs = append(msgs, e.String())
+			}
+			return nil, fmt.Errorf("invalid variables: %s", strings.Join(msgs, "; "))
+		}
+	}
+
+	return input, nil
+}
+
+// Resolve renders a prompt request into a plain chat request and implements
+// flows.PromptResolver. User messages become the user input, system messages
+// replace the system prompt, and the model and config frontmatter override
+// the model and options of the request.
+func (l *Library) Resolve(ctx context.Context, req flows.ChatRequest) (flows.ChatRequest, error) {
+	source, ok := l.sources[req.Prompt]
+	if !ok {
+		return req, fmt.Errorf("prompt not found: %s", req.Prompt)
+	}
+
+	l.mu.Lock()
+	rendered, err := l.dp.Render(source, &dotprompt.DataArgument{Input: req.PromptInput}, nil)
+	l.mu.Unlock()
+	if err != nil {
+		return req, fmt.Errorf("failed to render prompt %s: %w", req.Prompt, err)
+	}
+
+	var system, user []string
+	for _, msg := range rendered.Messages {
+		var text strings.Builder
+		for _, part := range msg.Content {
+			if p, ok := part.(*dotprompt.TextPart); ok {
+				text.WriteString(p.Text)
+			}
+		}
+		if msg.Role == dotprompt.RoleSystem {
+			system = append(system, strings.TrimSpace(text.String()))
+		} else {
+			user = append(user, strings.TrimSpace(text.String()))
+		}
+	}
+	if len(user) == 0 {
+		return req, fmt.Errorf("prompt %s has no user message", req.Prompt)
+	}
+
+	req.UserInput = strings.Join(user, "\n\n")
+	if len(system) > 0 {
+		req.SystemPrompt = strings.Join(system, "\n\n")
+	}
+
+	if rendered.Model != "" {
+		if genkit.LookupModel(l.g, rendered.Model) == nil {
+			return req, fmt.Errorf("model %s of prompt %s is not available", rendered.Model, req.Prompt)
+		}
+		req.Model = rendered.Model
+	}
+
+	if len(rendered.Config) > 0 {
+		options := maps.Clone(req.Options)
+		if options == nil {
+			options = make(map[string]any, len(rendered.Config))
+		}
+		maps.Copy(options, rendered.Config)
+		req.Options = options
+	}
+
+	req.Prompt = ""
+	req.PromptInput = nil
+	return req, nil
+}
+
+// Variables returns the variable names of a prompt with their types,
+// e.g. "topic: string", for display
+func (info Info) Variables() []string {
+	properties, _ := info.InputSchema["properties"].(map[string]any)
+	required, _ := info.InputSchema["required"].([]any)
+
+	var vars []string
+	for _, key := range slices.Sorted(maps.Keys(properties)) {
+		prop, _ := properties[key].(map[string]any)
+		v := key
+		if !slices.Contains(required, any(key)) {
+			v += "?"
+		}
+		if types := schemaTypes(prop); len(types) > 0 {
+			v += ": " + types[0]
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// schemaTypes returns the JSON types allowed by a property schema, without "null"
+func schemaTypes(prop map[string]any) []string {
+	var types []string
+	switch t := prop["type"].(type) {
+	case string:
+		types = append(types, t)
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				types = append(types, s)
+			}
+		}
+	}
+	return types
+}
+
+// convert parses a command line value into the first matching JSON type
+func convert(value string, types []string) (any, error) {
+	if len(types) == 0 {
+		return value, nil
+	}
+	for _, t := range types {
+		switch t {
+		case "string":
+			return value, nil
+		case "integer":
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return i, nil
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f, nil
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b, nil
+			}
+		case "array":
+			return strings.Split(value, ","), nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a valid %s", value, strings.Join(types, " or "))
+}
@@ -0,0 +1,7 @@
+package provider
+
+import "errors"
+
+// ErrUnreachable is matched by errors of requests that did not reach the
+// backend, e.g. because it is not running. Chat fails over on them.
+var ErrUnreachable = errors.New("cannot connect to the server")
@@ -0,0 +1,34 @@
+// Package provider describes model backends, such as Ollama servers and
+// servers with an OpenAI-compatible API
+package provider
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// Provider is a model backend whose models are registered with Genkit as
+// "<name>/<model>"
+type Provider interface {
+	// Name returns the prefix of the provider's models
+	Name() string
+
+	// Address returns the base URL of the backend
+	Address() string
+
+	// CheckHealth reports whether the backend answers
+	CheckHealth(ctx context.Context) error
+
+	// Register lists the models of the backend and defines the ones not yet
+	// known with Genkit. The context limits listing the models and fetching
+	// their details. It fails if the backend is unreachable.
+	Register(ctx context.Context, g *genkit.Genkit) (Models, error)
+}
+
+// Models are the identifiers of the models a backend serves, with the
+// provider's name as prefix
+type Models struct {
+	Chat      []string // defined as Genkit models
+	Embedders []string // defined as Genkit embedders
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// maxReadBytes limits how much of a file the read_file tool returns to the model
+const maxReadBytes = 64 * 1024
+
+// ConfirmFunc asks the user to approve a side-effecting tool call.
+// It returns true if the call may proceed.
+type ConfirmFunc func(toolName, summary string) bool
+
+type Option func(*Registry)
+
+// WithConfirm sets the function used to confirm side-effecting tool calls
+func WithConfirm(fn ConfirmFunc) Option {
+	return func(r *Registry) {
+		r.confirm = fn
+	}
+}
+
+// Registry holds the tools defined with Genkit and which of them need confirmation
+type Registry struct {
+	tools         []ai.Tool
+	sideEffecting map[string]bool
+
+	// confirmMu serializes confirmations, Genkit runs tool requests concurrently
+	confirmMu sync.Mutex
+	confirm   ConfirmFunc
+}
+
+// ReadFileInput is the input of the read_file tool
+type ReadFileInput struct {
+	Path string `json:"path" jsonschema_description:"Path of the file to read"`
+}
+
+// ListDirectoryInput is the input of the list_directory tool
+type ListDirectoryInput struct {
+	Path string `json:"path" jsonschema_description:"Path of the directory to list, defaults to the current directory"`
+}
+
+// WriteFileInput is the input of the write_file tool
+type WriteFileInput struct {
+	Path    string `json:"path" jsonschema_description:"Path of the file to write"`
+	Content string `json:"content" jsonschema_description:"Full content to write to the file"`
+}
+
+// NewRegistry defines all built-in tools with Genkit and returns the registry
+func NewRegistry(g *genkit.Genkit, opts ...Option) *Registry {
+	r := &Registry{
+		sideEffecting: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.define(genkit.DefineTool(g, "current_time",
+		"Returns the current local date and time",
+		func(ctx *ai.ToolContext, _ struct{}) (string, error) {
+			return time.Now().Format(time.RFC1123), nil
+		}), false)
+
+	r.define(genkit.DefineTool(g, "read_file",
+		"Reads a text file from the local filesystem and returns its content",
+		func(ctx *ai.ToolContext, in ReadFileInput) (string, error) {
+			data, err := os.ReadFile(expandPath(in.Path))
+			if err != nil {
+				return "", fmt.Errorf("failed to read file: %w", err)
+			}
+			if len(data) > maxReadBytes {
+				return string(data[:maxReadBytes]) + "\n... (truncated)", nil
+			}
+			return string(data), nil
+		}), false)
+
+	r.define(genkit.DefineTool(g, "list_directory",
+		"Lists the entries of a directory on the local filesystem",
+		func(ctx *ai.ToolContext, in ListDirectoryInput) ([]string, error) {
+			path := in.Path
+			if path == "" {
+				path = "."
+			}
+			entries, err := os.ReadDir(expandPath(path))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list directory: %w", err)
+			}
+			names := make([]string, 0, len(entries))
+			for _, e := range entries {
+				name := e.Name()
+				if e.IsDir() {
+					name += "/"
+				}
+				names = append(names, name)
+			}
+			return names, nil
+		}), false)
+
+	r.define(genkit.DefineTool(g, "write_file",
+		"Writes content to a file on the local filesystem, replacing it if it exists",
+		func(ctx *ai.ToolContext, in WriteFileInput) (string, error) {
+			summary := fmt.Sprintf("write %d bytes to %s", len(in.Content), in.Path)
+			if !r.approve("write_file", summary) {
+				return "The user declined this action", nil
+			}
+			if err := os.WriteFile(expandPath(in.Path), []byte(in.Content), 0644); err != nil {
+				return "", fmt.Errorf("failed to write file: %w", err)
+			}
+			return fmt.Sprintf("Wrote %d bytes to %s", len(in.Content), in.Path), nil
+		}), true)
+
+	return r
+}
+
+// define records a tool defined with Genkit
+func (r *Registry) define(tool ai.Tool, sideEffecting bool) {
+	r.tools = append(r.tools, tool)
+	if sideEffecting {
+		r.sideEffecting[tool.Name()] = true
+	}
+	slog.Debug("Registered tool", "name", tool.Name(), "side_effecting", sideEffecting)
+}
+
+// Names returns the names of all registered tools
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.tools))
+	for _, t := range r.tools {
+		names = append(names, t.Name())
+	}
+	return names
+}
+
+// All returns all registered tools
+func (r *Registry) All() []ai.Tool {
+	return slices.Clone(r.tools)
+}
+
+// IsSideEffecting reports whether a tool needs user confirmation before running
+func (r *Registry) IsSideEffecting(name string) bool {
+	return r.sideEffecting[name]
+}
+
+// approve asks for confirmation of a side-effecting call.
+// Calls are denied when no confirm function is configured.
+func (r *Registry) approve(toolName, summary string) bool {
+	r.confirmMu.Lock()
+	defer r.confirmMu.Unlock()
+
+	if r.confirm == nil {
+		slog.Warn("Tool call denied, no confirmation handler", "tool", toolName)
+		return false
+	}
+	approved := r.confirm(toolName, summary)
+	slog.Info("Tool call confirmation", "tool", toolName, "summary", summary, "approved", approved)
+	return approved
+}
+
+// expandPath expands a leading ~/ to the user's home directory
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
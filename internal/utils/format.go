@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 GB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ProgressBar renders a bar of the given width filled to completed/total
+func ProgressBar(completed, total int64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(width) * float64(min(completed, total)) / float64(total))
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// WaitIndicator shows a status line with the elapsed time while waiting,
+// e.g. for a model to load before the first chunk arrives.
+// The line appears only after a delay so fast responses do not flicker.
+type WaitIndicator struct {
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StartWaitIndicator prints "label Ns" after delay and updates it every second
+// until Stop is called
+func StartWaitIndicator(label string, delay time.Duration, c *color.Color) *WaitIndicator {
+	w := &WaitIndicator{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		start := time.Now()
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(delay):
+		}
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			c.Printf("\r%s %ds", label, int(time.Since(start).Seconds()))
+			select {
+			case <-w.stop:
+				fmt.Print("\r\033[K") // Clear the status line
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return w
+}
+
+// Stop removes the status line. It waits until the line is cleared,
+// so output printed afterwards is not overwritten. Safe to call more than once.
+func (w *WaitIndicator) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -16,21 +20,27 @@ import (
 	"time"
 
 	"tchat/internal/appstate"
+	"tchat/internal/catalog"
 	"tchat/internal/command"
 	"tchat/internal/config"
 	"tchat/internal/db"
+	"tchat/internal/devserver"
 	"tchat/internal/flows"
 	"tchat/internal/history"
+	"tchat/internal/httpclient"
 	"tchat/internal/logging"
 	"tchat/internal/media"
 	ollamahelper "tchat/internal/ollama"
+	"tchat/internal/openai"
+	"tchat/internal/prompts"
+	"tchat/internal/provider"
+	"tchat/internal/tools"
 	"tchat/internal/utils"
 	"tchat/internal/version"
 
 	"github.com/chzyer/readline"
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
-	"github.com/firebase/genkit/go/plugins/ollama"
 	"github.com/google/uuid"
 	"golang.design/x/clipboard"
 )
@@ -38,7 +48,23 @@ import (
 // lastResponse stores the last AI response for clipboard copy
 var lastResponse = ""
 
+// lastReasoning stores the thinking output of the last AI response
+var lastReasoning = ""
+
+// reconnectInterval is how often model discovery is retried while no model
+// is available for chat
+const reconnectInterval = 10 * time.Second
+
 func main() {
+	// "tchat dev" serves the flows over HTTP instead of starting the chat
+	devMode := len(os.Args) > 1 && os.Args[1] == "dev"
+	devAddr := devserver.DefaultAddr
+	if devMode {
+		devFlags := flag.NewFlagSet("dev", flag.ExitOnError)
+		devFlags.StringVar(&devAddr, "addr", devserver.DefaultAddr, "address to serve the flows on")
+		devFlags.Parse(os.Args[2:])
+	}
+
 	fmt.Printf("Initializing...\n")
 
 	// Initialize configuration
@@ -85,46 +111,101 @@ func main() {
 
 	ctx := context.Background()
 
-	ollamaHost := os.Getenv("OLLAMA_HOST")
-	if ollamaHost == "" {
-		ollamaHost = "http://localhost:11434"
+	// Configured hosts replace OLLAMA_HOST; the first one is the primary host
+	hosts := cfg.Hosts()
+	if len(hosts) == 0 {
+		ollamaHost := os.Getenv("OLLAMA_HOST")
+		if ollamaHost == "" {
+			ollamaHost = "http://localhost:11434"
+		}
+		hosts = append(hosts, config.HostConfig{Name: ollamahelper.DefaultHostName, URL: ollamaHost})
+	}
+
+	// All outbound calls share one transport: CA bundle, proxy and headers
+	// for the hosts, e.g. a bearer token for a reverse proxy
+	httpSettings := cfg.HTTP()
+	httpConfig := httpclient.Config{
+		Timeout:        httpSettings.Timeout,
+		ConnectTimeout: httpSettings.ConnectTimeout,
+		Headers:        httpSettings.Headers,
+		CAFile:         httpSettings.CAFile,
+		Proxy:          httpSettings.Proxy,
 	}
-	ollamaObj := &ollama.Ollama{
-		ServerAddress: ollamaHost,
-		Timeout:       300, // 5 minutes
+	for _, h := range hosts {
+		httpConfig.Hosts = append(httpConfig.Hosts, h.URL)
+	}
+	if err := httpclient.Configure(httpConfig); err != nil {
+		slog.Error("Failed to configure the HTTP client", "error", err)
+		cfg.ErrorColor().Printf("Error: invalid http configuration: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Initialize Genkit with the Google AI plugin
+	// Ollama hosts share one cache of model capabilities
+	modelCache := ollamahelper.LoadModelCache(filepath.Join(cfg.GetAppDir(), "model_cache.json"))
+	var providers []provider.Provider
+	for _, h := range hosts {
+		if h.Type == config.HostTypeOpenAI {
+			client := &http.Client{Transport: httpclient.Transport(), Timeout: openai.DefaultTimeout}
+			providers = append(providers, openai.New(h.Name, h.URL, openai.WithAPIKey(h.APIKey), openai.WithHTTPClient(client)))
+			continue
+		}
+		providers = append(providers, ollamahelper.New(h.Name, h.URL,
+			ollamahelper.WithModelCache(modelCache), ollamahelper.WithTransport(httpclient.Transport())))
+	}
+
+	// Models are defined by tchat's own generators, so Genkit needs no
+	// plugins; setups with OpenAI-compatible hosts only work the same way
 	fmt.Printf("• Initializing Genkit...\n")
-	g := genkit.Init(ctx,
-		genkit.WithPlugins(ollamaObj),
-	)
+	g := genkit.Init(ctx)
 	cfg.InfoColor().Printf("  ✓ Genkit ready\n")
 
-	// Register Ollama models
-	fmt.Printf("• Discovering Ollama models...\n")
-	availableModels, err := ollamahelper.RegisterModels(g, ollamaObj, ollamaHost)
+	// Register the models of all hosts
+	fmt.Printf("• Discovering models...\n")
+	modelCatalog := catalog.New(g, providers)
+	availableModels, err := modelCatalog.Discover()
 	if err != nil {
-		slog.Error("Failed to register Ollama models", "error", err)
-		cfg.ErrorColor().Printf("  x Failed to register ollama models")
-		os.Exit(1)
+		slog.Error("Failed to discover models", "error", err)
+		cfg.ErrorColor().Printf("  x Failed to discover models: %v\n", err)
+		printHostRemediation(cfg, modelCatalog)
 	}
-
-	if len(availableModels) == 0 {
-		slog.Warn("No local ollama models are available",
-			"message", "Run `ollama pull <model_name> to pull a model. Visit https://ollama.com for more details")
-		cfg.ErrorColor().Printf("  x No local ollama models available\n")
-		os.Exit(1)
+	if len(providers) > 1 {
+		for _, h := range modelCatalog.Hosts() {
+			if h.Healthy {
+				cfg.InfoColor().Printf("  ✓ %s (%s): %d models\n", h.Name, h.Address, h.Models)
+			} else {
+				cfg.ErrorColor().Printf("  ✗ %s (%s): unreachable\n", h.Name, h.Address)
+			}
+		}
 	}
-	cfg.InfoColor().Printf("  ✓ Found %d models\n", len(availableModels))
 
-	// set model from config. If not available, choose one from available ollama models
+	// Without models, start offline: history and storage commands work, and
+	// chat is enabled once discovery succeeds in the background
 	currentModel := cfg.GetModel()
-	if currentModel == "" || !slices.Contains(availableModels, currentModel) {
-		currentModel = availableModels[0] // Use first Ollama model by default
+	if len(availableModels) == 0 {
+		if err == nil {
+			slog.Warn("No models are available",
+				"message", "Run `ollama pull <model_name> to pull a model. Visit https://ollama.com for more details")
+			cfg.ErrorColor().Printf("  x No models available. Pull one with /pull <model>\n")
+		}
+		cfg.ErrorColor().Printf("  ⚠ Starting offline; chat is enabled once a model is available\n")
+	} else {
+		cfg.InfoColor().Printf("  ✓ Found %d models\n", len(availableModels))
+		currentModel = selectModel(cfg, availableModels, currentModel)
+		cfg.InfoColor().Printf("  ✓ Using model: %s\n", currentModel)
 	}
 
-	cfg.InfoColor().Printf("  ✓ Using model: %s\n", currentModel)
+	// Keep models loaded as configured and load the current one in the background
+	keepAlive := cfg.KeepAlive()
+	defaultKeepAlive := keepAlive["default"]
+	delete(keepAlive, "default")
+	ollamahelper.SetKeepAlive(defaultKeepAlive, keepAlive)
+	if modelCatalog.Contains(currentModel) && modelCatalog.IsOllama(currentModel) {
+		go func() {
+			if err := ollamahelper.Preload(ctx, modelCatalog.AddressOf(currentModel), currentModel); err != nil {
+				slog.Warn("Failed to preload model", "model", currentModel, "error", err)
+			}
+		}()
+	}
 
 	// Initialize history manager
 	historyMgr := history.NewHistoryManager(history.WithMaxMessages(5))
@@ -156,10 +237,14 @@ func main() {
 
 	// Initialize app state
 	fmt.Printf("• Initializing app state...\n")
-	state, err := appstate.New(
-		appstate.WithModel(currentModel),
+	stateOpts := []appstate.Option{
 		appstate.WithSystemPrompt(cfg.GetSystemPrompt()),
-	)
+		appstate.WithShowThinking(config.DefaultShowThinking),
+	}
+	if currentModel != "" {
+		stateOpts = append(stateOpts, appstate.WithModel(currentModel))
+	}
+	state, err := appstate.New(stateOpts...)
 	if err != nil {
 		slog.Error("App state creation faile", "error", err)
 		cfg.ErrorColor().Printf("  ⚠ App state creation failed\n")
@@ -167,17 +252,74 @@ func main() {
 	}
 	fmt.Printf("  ✓ App state created and initialized\n")
 
+	// Load dotprompt files
+	fmt.Printf("• Loading prompts...\n")
+	promptLib, err := prompts.Load(g, filepath.Join(cfg.GetAppDir(), "prompts"))
+	if err != nil {
+		slog.Warn("Failed to load prompts", "error", err)
+		fmt.Printf("  ⚠ Prompts disabled: %v\n", err)
+	} else {
+		cfg.InfoColor().Printf("  ✓ Loaded %d prompts\n", len(promptLib.Names()))
+	}
+
 	// Initialize command registry
-	cmdRegistry := command.InitializeRegistry(availableModels, store)
+	cmdRegistry := command.InitializeRegistry(modelCatalog, store, promptLib)
 
 	// Initialize chat flow with dependencies
-	chatFlow := flows.NewChatFlow(g)
+	flowOpts := []flows.Option{flows.WithDefaultModel(currentModel)}
+	if promptLib != nil {
+		flowOpts = append(flowOpts, flows.WithPromptResolver(promptLib.Resolve))
+	}
+	for _, name := range cfg.Middleware() {
+		middleware, err := flows.NewMiddleware(name, cfg.GetAppDir())
+		if err != nil {
+			cfg.ErrorColor().Printf("  ✗ %v\n", err)
+			continue
+		}
+		flowOpts = append(flowOpts, flows.WithMiddleware(middleware))
+		slog.Info("Middleware enabled", "name", name)
+	}
+	chatFlow := flows.NewChatFlow(g, flowOpts...)
+
+	// Define tools; side-effecting tools ask for confirmation before running
+	toolRegistry := tools.NewRegistry(g, tools.WithConfirm(func(toolName, summary string) bool {
+		if devMode {
+			// Nobody can answer the prompt when serving flows
+			slog.Warn("Tool call denied in dev mode", "tool", toolName, "action", summary)
+			return false
+		}
+		fmt.Println()
+		cfg.ErrorColor().Printf("⚠ Tool %s wants to %s\n", toolName, summary)
+		answer, err := command.ReadInputWithoutHistory("Allow? [y/N]: ")
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+	}))
+	slog.Info("Tools registered", "tools", toolRegistry.Names())
+
+	if devMode {
+		serveFlows(cfg, g, devAddr)
+		return
+	}
 
 	// Setup readline with history
 	historyFile := filepath.Join(cfg.GetAppDir(), "history")
 
+	// The prompt shows when chat is unavailable
+	prompt := func() string {
+		switch {
+		case !modelCatalog.Online():
+			return cfg.PromptColor().Sprint("tchat ") + cfg.ErrorColor().Sprint("(offline)") + cfg.PromptColor().Sprint("> ")
+		case len(modelCatalog.Models()) == 0:
+			return cfg.PromptColor().Sprint("tchat ") + cfg.ErrorColor().Sprint("(no models)") + cfg.PromptColor().Sprint("> ")
+		default:
+			return cfg.PromptColor().Sprint("tchat> ")
+		}
+	}
+
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          cfg.PromptColor().Sprint("tchat> "),
+		Prompt:          prompt(),
 		HistoryFile:     historyFile,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
@@ -189,6 +331,101 @@ func main() {
 	}
 	defer rl.Close()
 
+	// Model changes found in the background are only reported there. They
+	// are applied on the REPL goroutine between turns, so a running
+	// generation keeps its model and history.
+	var changesMu sync.Mutex
+	var pendingChanges []catalog.ModelChanges
+	applyModelChanges := func() {
+		changesMu.Lock()
+		pending := pendingChanges
+		pendingChanges = nil
+		changesMu.Unlock()
+		for _, changes := range pending {
+			command.ApplyModelChanges(&command.CommandContext{
+				Ctx:     ctx,
+				Config:  cfg,
+				State:   state,
+				History: historyMgr,
+			}, modelCatalog, changes)
+		}
+	}
+
+	// Poll Ollama for pulled and removed models when configured
+	if interval := cfg.ModelRefreshInterval(); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				changes, err := modelCatalog.Refresh()
+				if err != nil {
+					slog.Warn("Failed to refresh models", "error", err)
+				}
+				if changes.Empty() {
+					continue
+				}
+				fmt.Println()
+				command.ReportModelChanges(&command.CommandContext{Ctx: ctx, Config: cfg}, changes)
+				changesMu.Lock()
+				pendingChanges = append(pendingChanges, changes)
+				changesMu.Unlock()
+				rl.Refresh()
+			}
+		}()
+	}
+
+	// discover lists the models again while the current model is
+	// unavailable. Reports whether a model is available.
+	var discoverMu sync.Mutex
+	discover := func() bool {
+		discoverMu.Lock()
+		defer discoverMu.Unlock()
+		if modelCatalog.Contains(state.GetModel()) {
+			return true
+		}
+		if _, err := modelCatalog.Refresh(); err != nil {
+			slog.Debug("Reconnect failed", "error", err)
+		}
+		return len(modelCatalog.Models()) > 0
+	}
+
+	// selectAvailable selects a model once there is one while the current
+	// model is unavailable, and returns it. It changes the model, so call
+	// it on the REPL goroutine between turns.
+	selectAvailable := func() string {
+		models := modelCatalog.Models()
+		if modelCatalog.Contains(state.GetModel()) || len(models) == 0 {
+			return ""
+		}
+		model := selectModel(cfg, models, state.GetModel())
+		state.SetModel(model)
+		slog.Info("Chat enabled", "model", model)
+		if modelCatalog.IsOllama(model) {
+			go func() {
+				if err := ollamahelper.Preload(ctx, modelCatalog.AddressOf(model), model); err != nil {
+					slog.Warn("Failed to preload model", "model", model, "error", err)
+				}
+			}()
+		}
+		return model
+	}
+
+	// Retry discovery in the background while offline; the prompt shows
+	// when chat is available again
+	go func() {
+		ticker := time.NewTicker(reconnectInterval)
+		defer ticker.Stop()
+		shown := prompt()
+		for range ticker.C {
+			discover()
+			if current := prompt(); current != shown {
+				shown = current
+				rl.SetPrompt(current)
+				rl.Refresh()
+			}
+		}
+	}()
+
 	// Setup signal handling for Ctrl-C
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT)
@@ -202,15 +439,201 @@ func main() {
 		for range sigChan {
 			mu.Lock()
 			if genCancel != nil {
-				// Cancel ongoing generation
+				// Cancel ongoing generation or download
 				genCancel()
 				fmt.Println() // New line after ^C
-				cfg.ErrorColor().Println("Generation canceled by user")
+				cfg.ErrorColor().Println("Canceled by user")
 			}
 			mu.Unlock()
 		}
 	}()
 
+	// cancelOnInterrupt returns a context that Ctrl-C cancels until the
+	// returned function is called
+	cancelOnInterrupt := func(parent context.Context) (context.Context, context.CancelFunc) {
+		mu.Lock()
+		defer mu.Unlock()
+		opCtx, cancel := context.WithCancel(parent)
+		genCancel = cancel
+		return opCtx, func() {
+			cancel()
+			mu.Lock()
+			genCancel = nil
+			mu.Unlock()
+		}
+	}
+
+	// generateOnce runs a chat request with streaming output; Ctrl-C cancels it.
+	// Errors are reported to the user before returning.
+	generateOnce := func(reqCtx context.Context, chatReq flows.ChatRequest) (flows.ChatResponse, error) {
+		// Create cancellable context for this generation
+		genCtx, cancel := cancelOnInterrupt(reqCtx)
+		defer cancel()
+
+		// Log generation start
+		slog.Info("Generation started",
+			"model", chatReq.Model,
+			"input_length", len(chatReq.UserInput),
+			"history_messages", len(chatReq.History),
+			"images", len(chatReq.ImagePaths),
+			"options", chatReq.Options,
+			"input", chatReq.UserInput,
+		)
+
+		// Offer tools only to models that advertise the tools capability
+		chatReq.Tools = nil
+		if ollamahelper.SupportsTools(chatReq.Model) {
+			chatReq.Tools = toolRegistry.Names()
+		}
+
+		// Shows the time spent waiting for the first chunk
+		var waiting *utils.WaitIndicator
+
+		// Prepare streaming callback
+		firstChunk := true
+		showThinking := state.ShowThinking()
+		thinking := false
+		streamCallback := flows.StreamCallback(func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+			if firstChunk {
+				waiting.Stop()
+				fmt.Println()
+				firstChunk = false
+			}
+			for _, part := range chunk.Content {
+				switch {
+				case part.IsReasoning():
+					if !thinking {
+						cfg.ThinkingColor().Print("💭 ")
+						if !showThinking {
+							cfg.ThinkingColor().Print("Thinking...")
+						}
+						thinking = true
+					}
+					if showThinking {
+						cfg.ThinkingColor().Print(part.Text)
+					}
+				case part.IsText():
+					if thinking {
+						fmt.Print("\n\n")
+						thinking = false
+					}
+					cfg.OutputColor().Print(part.Text)
+				case part.IsToolRequest():
+					cfg.InfoColor().Printf("\n🔧 %s(%s)\n", part.ToolRequest.Name, formatToolValue(part.ToolRequest.Input))
+				case part.IsToolResponse():
+					cfg.InfoColor().Printf("  ↳ %s\n", formatToolValue(part.ToolResponse.Output))
+				}
+			}
+			return nil
+		})
+
+		var resp flows.ChatResponse
+		var err error
+		jsonMode, _, jsonSchema := state.JSONMode()
+		if jsonMode {
+			// JSON answers are validated before display, so they are not streamed
+			chatReq.OutputSchema = jsonSchema
+			cfg.InfoColor().Println("{} Generating JSON...")
+			resp, err = chatFlow.RunJSON(genCtx, chatReq, flows.DefaultJSONAttempts, func(attempt int, err error) {
+				cfg.ErrorColor().Printf("✗ Attempt %d/%d invalid: %v\n", attempt, flows.DefaultJSONAttempts, err)
+			})
+			if err == nil {
+				pretty, _ := json.MarshalIndent(resp.JSON, "", "  ")
+				resp.Output = string(pretty)
+				cfg.OutputColor().Printf("\n%s", resp.Output)
+			}
+		} else {
+			// Execute chat flow with streaming. The first request after
+			// switching models waits for Ollama to load the model.
+			waiting = utils.StartWaitIndicator(fmt.Sprintf("⏳ Loading model %s...", chatReq.Model), time.Second, cfg.InfoColor())
+			resp, err = chatFlow.RunWithStreaming(genCtx, chatReq, streamCallback)
+			waiting.Stop()
+		}
+
+		if err != nil {
+			// Check if it was cancelled or ran out of time
+			if genCtx.Err() != nil {
+				slog.Info("Generation cancelled",
+					"reason", genCtx.Err(),
+					"model", chatReq.Model,
+					"duration_ms", resp.DurationMs,
+					"partial_length", len(resp.Output),
+				)
+				err = genCtx.Err()
+			} else {
+				// some other error
+				slog.Error("Generation failed",
+					"error", err,
+					"duration_ms", resp.DurationMs,
+					"model", chatReq.Model,
+					"partial_length", len(resp.Output),
+				)
+				cfg.ErrorColor().Printf("Error generating response: %v\n", err)
+				host, _ := modelCatalog.Resolve(chatReq.Model)
+				printRemediation(cfg, err, host, chatReq.Model)
+			}
+
+			if resp.Output != "" {
+				cfg.InfoColor().Printf("Partial answer saved (%d characters). Use /continue to resume\n", len(resp.Output))
+			}
+			return resp, err
+		}
+
+		cfg.OutputColor().Println()
+
+		// Point to collapsed thinking
+		if resp.Reasoning != "" && !showThinking {
+			cfg.ThinkingColor().Printf("(%d characters of thinking hidden, /think last to show)\n", len(resp.Reasoning))
+		}
+
+		// Show images loaded if any
+		if resp.ImagesLoaded > 0 {
+			cfg.InfoColor().Printf("✓ Processed %d image(s)\n", resp.ImagesLoaded)
+		}
+
+		// Log generation success with metadata
+		slog.Info("Generation completed",
+			"model", chatReq.Model,
+			"duration_ms", resp.DurationMs,
+			"ttfc_ms", resp.TTFCMs,
+			"chunks", resp.Chunks,
+			"output_length", len(resp.Output),
+			"reasoning_length", len(resp.Reasoning),
+			"input_length", len(chatReq.UserInput),
+			"images_loaded", resp.ImagesLoaded,
+			"tool_calls", len(resp.ToolCalls),
+			"json_attempts", resp.Attempts,
+		)
+
+		return resp, nil
+	}
+
+	// generate runs a chat request and, if the host of the current model
+	// cannot be reached, fails over to the fallback model and runs it again
+	generate := func(reqCtx context.Context, chatReq flows.ChatRequest) (flows.ChatResponse, error) {
+		resp, err := generateOnce(reqCtx, chatReq)
+		if resp.Output != "" || chatReq.Model != state.GetModel() {
+			return resp, err
+		}
+		if !errors.Is(err, provider.ErrUnreachable) {
+			return resp, err
+		}
+		modelCatalog.MarkUnreachable(chatReq.Model, err)
+
+		fallback := cfg.FallbackModel()
+		if fallback == "" || fallback == chatReq.Model || !modelCatalog.Contains(fallback) {
+			return resp, err
+		}
+		slog.Warn("Failing over", "from", chatReq.Model, "to", fallback)
+		cfg.ErrorColor().Printf("⚠ Failing over to %s; the conversation continues there\n", fallback)
+		state.SetModel(fallback)
+		chatReq.Model = fallback
+		return generateOnce(reqCtx, chatReq)
+	}
+
+	// lastTurn records the last chat turn for /retry and /pick
+	lastTurn := &command.Turn{}
+
 	// Show ready message
 	fmt.Println()
 	fmt.Printf("\nReady! Type /help for available commands\n")
@@ -229,6 +652,11 @@ func main() {
 
 	// Main read loop
 	for {
+		applyModelChanges()
+		if model := selectAvailable(); model != "" {
+			cfg.InfoColor().Printf("✓ Models are available. Chat enabled with %s\n", model)
+		}
+		rl.SetPrompt(prompt())
 		line, err := rl.Readline()
 		if err != nil {
 			if err == readline.ErrInterrupt {
@@ -242,6 +670,9 @@ func main() {
 			break
 		}
 
+		// Changes found while waiting for input apply to this turn already
+		applyModelChanges()
+
 		userInput := strings.TrimSpace(line)
 		if userInput == "" {
 			continue
@@ -249,13 +680,20 @@ func main() {
 		// Special commands
 		if cmdRegistry.IsCommand(userInput) {
 			cmd, _ := cmdRegistry.Get(userInput)
+			_, args := command.Parse(userInput)
 			cmdCtx := &command.CommandContext{
-				Ctx:          ctx,
-				Config:       cfg,
-				State:        state,
-				Readline:     rl,
-				History:      historyMgr,
-				LastResponse: &lastResponse,
+				Ctx:           ctx,
+				Config:        cfg,
+				State:         state,
+				Readline:      rl,
+				History:       historyMgr,
+				LastResponse:  &lastResponse,
+				LastReasoning: &lastReasoning,
+				SessionId:     sessionId,
+				LastTurn:      lastTurn,
+				Generate:      generate,
+				Interruptible: cancelOnInterrupt,
+				Args:          args,
 			}
 			result := cmd.Execute(cmdCtx)
 			if result == command.REPLExit {
@@ -271,102 +709,77 @@ func main() {
 			continue
 		}
 
+		// Attach the files referenced with @path; the turn stores the input
+		// as typed, the history what the model saw
+		input, attachments, ok := command.AttachFiles(&command.CommandContext{Config: cfg}, userInput)
+		if !ok {
+			continue
+		}
+
+		// Chat needs a model; try to reconnect right away instead of waiting
+		available := discover()
+		model := selectAvailable()
+		switch {
+		case model != "":
+			cfg.InfoColor().Printf("✓ Models are available. Chat enabled with %s\n", model)
+		case !available && modelCatalog.Online():
+			cfg.ErrorColor().Println("No models available, chat is disabled. Pull one with /pull <model>")
+			continue
+		case !available:
+			cfg.ErrorColor().Println("No host is reachable, chat is disabled until one is back")
+			fmt.Println("History and storage commands work offline. Type /help to see them")
+			printHostRemediation(cfg, modelCatalog)
+			continue
+		}
+
 		// Detect images in user input
 		imagePaths := media.ExtractImagePaths(userInput)
 		if len(imagePaths) > 0 {
 			cfg.InfoColor().Printf("📷 Detected %d image(s): %v\n", len(imagePaths), imagePaths)
 		}
 
-		// Create cancellable context for this generation
-		mu.Lock()
-		genCtx, cancel := context.WithCancel(ctx)
-		genCancel = cancel
-		mu.Unlock()
-
-		cleanup := func() {
-			cancel()
-			mu.Lock()
-			genCancel = nil
-			mu.Unlock()
+		// Models without vision get no images; the user decides where they go
+		route, ok := command.RouteImages(&command.CommandContext{
+			Ctx:     ctx,
+			Config:  cfg,
+			State:   state,
+			History: historyMgr,
+		}, modelCatalog, imagePaths)
+		if !ok {
+			continue
 		}
 
-		// Log generation start
 		startTime := time.Now()
-		slog.Info("Generation started",
-			"model", state.GetModel(),
-			"input_length", len(userInput),
-			"history_messages", historyMgr.Count(),
-			"images", len(imagePaths),
-			"input", userInput,
-		)
-
-		// Prepare streaming callback
-		firstChunk := true
-		streamCallback := flows.StreamCallback(func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
-			if firstChunk {
-				fmt.Println()
-				firstChunk = false
-			}
-			cfg.OutputColor().Printf("%s", chunk.Text())
-			return nil
-		})
-
-		// Execute chat flow with streaming
-		resp, err := chatFlow.RunWithStreaming(genCtx, flows.ChatRequest{
-			UserInput:    userInput,
-			Model:        state.GetModel(),
+		chatReq := flows.ChatRequest{
+			UserInput:    input,
+			Model:        route.Model,
 			SystemPrompt: state.GetSystemPrompt(),
 			History:      historyMgr.GetAll(),
-			ImagePaths:   imagePaths,
-		}, streamCallback)
-
-		if err != nil {
-			// Check if it was cancelled
-			if genCtx.Err() == context.Canceled {
-				slog.Info("Generation cancelled by user",
-					"model", state.GetModel(),
-					"duration_ms", resp.DurationMs,
-				)
-				cleanup()
-				continue
-			}
-
-			// some other error
-			slog.Error("Generation failed",
-				"error", err,
-				"duration_ms", resp.DurationMs,
-				"model", state.GetModel(),
-			)
-			cfg.ErrorColor().Printf("Error generating response: %v\n", err)
-			cleanup()
-			continue
+			ImagePaths:   route.ImagePaths,
+			Options:      state.Params(),
 		}
 
-		cfg.OutputColor().Println()
+		// Cancelled and failed generations are saved with their partial answer
+		resp, err := generate(ctx, chatReq)
+		status := command.StatusOf(err)
 
-		// Show images loaded if any
-		if resp.ImagesLoaded > 0 {
-			cfg.InfoColor().Printf("✓ Processed %d image(s)\n", resp.ImagesLoaded)
+		// Failing over changes the model that answered; a turn routed to
+		// the vision model keeps it
+		if route.Decision != command.RouteVisionModel {
+			chatReq.Model = state.GetModel()
 		}
 
-		// Log generation success with metadata
-		slog.Info("Generation completed",
-			"model", state.GetModel(),
-			"duration_ms", resp.DurationMs,
-			"ttfc_ms", resp.TTFCMs,
-			"chunks", resp.Chunks,
-			"output_length", len(resp.Output),
-			"input_length", len(userInput),
-			"images_loaded", resp.ImagesLoaded,
-		)
-
 		// Save to database
+		var msgID int64
 		if store != nil {
 			turn := db.ConversationTurn{
 				SessionId:    sessionId,
 				Timestamp:    startTime,
 				UserInput:    userInput,
 				ModelOutput:  resp.Output,
+				Reasoning:    resp.Reasoning,
+				Status:       status,
+				Routing:      route.String(),
 				DurationMs:   resp.DurationMs,
 				TTFCMs:       resp.TTFCMs,
 				Chunks:       resp.Chunks,
@@ -377,18 +790,34 @@ func main() {
 				slog.Error("Failed to save conversation to database", "error", err)
 			} else {
 				slog.Debug("Conversation saved", "id", id)
+				msgID = id
+				if err := store.SaveToolCalls(id, command.ToDBToolCalls(resp.ToolCalls)); err != nil {
+					slog.Error("Failed to save tool calls to database", "error", err)
+				}
+				if err := store.SaveAttachments(id, command.ToDBAttachments(attachments)); err != nil {
+					slog.Error("Failed to save attachments to database", "error", err)
+				}
 			}
 		}
 
-		// Update conversation history
-		historyMgr.AddUserMessage(state.GetModel(), userInput)
-		historyMgr.AddAssistantMessage(state.GetModel(), resp.Output)
+		if err != nil && resp.Output == "" {
+			continue
+		}
+
+		// Update conversation history; partial answers only if configured
+		inHistory := err == nil || cfg.KeepPartial()
+		if inHistory {
+			historyMgr.AddUserMessage(state.GetModel(), input)
+			historyMgr.AddAssistantMessage(state.GetModel(), resp.Output)
+		}
+
+		// Remember the turn so it can be regenerated with /retry or resumed with /continue
+		*lastTurn = command.NewTurn(msgID, chatReq, resp, status)
+		lastTurn.InHistory = inHistory
 
 		// Store last response for clipboard copy
 		lastResponse = resp.Output
-
-		// Cleanup generation state
-		cleanup()
+		lastReasoning = resp.Reasoning
 	}
 
 	// Save history on exit
@@ -407,3 +836,77 @@ func main() {
 
 	fmt.Println("Goodbye!")
 }
+
+// selectModel returns the model to chat with: the given one if it is
+// available, otherwise the fallback model, otherwise the first available one
+func selectModel(cfg *config.Config, models []string, model string) string {
+	if model != "" && slices.Contains(models, model) {
+		return model
+	}
+	if fallback := cfg.FallbackModel(); fallback != "" && slices.Contains(models, fallback) {
+		if model != "" {
+			cfg.ErrorColor().Printf("  ⚠ %s is unavailable, falling back to %s\n", model, fallback)
+		}
+		return fallback
+	}
+	return models[0] // Use first Ollama model by default
+}
+
+// printRemediation suggests a fix for a classified error of a host. Only
+// Ollama hosts get Ollama's remediation.
+func printRemediation(cfg *config.Config, err error, host catalog.Host, model string) {
+	if oe, ok := ollamahelper.AsError(err); ok && host.IsOllama() {
+		if hint := oe.Remediation(host.Address, model); hint != "" {
+			cfg.InfoColor().Printf("💡 %s\n", hint)
+		}
+		return
+	}
+	if errors.Is(err, provider.ErrUnreachable) {
+		cfg.InfoColor().Printf("💡 Check that the server of %s is running and reachable at %s\n", host.Name, host.Address)
+	}
+}
+
+// printHostRemediation suggests fixes for the hosts that are unreachable
+func printHostRemediation(cfg *config.Config, modelCatalog *catalog.Catalog) {
+	for _, h := range modelCatalog.Hosts() {
+		if !h.Healthy {
+			printRemediation(cfg, h.Err, h.Host, "")
+		}
+	}
+}
+
+// formatToolValue renders a tool input or output as compact JSON for display
+func formatToolValue(v any) string {
+	const maxLen = 200
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(b) > maxLen {
+		return string(b[:maxLen]) + "..."
+	}
+	return string(b)
+}
+
+// serveFlows serves the registered flows over HTTP until interrupted
+func serveFlows(cfg *config.Config, g *genkit.Genkit, addr string) {
+	server, err := devserver.New(g, addr, filepath.Join(cfg.GetAppDir(), "traces"))
+	if err != nil {
+		cfg.ErrorColor().Printf("Error: %v\n", err)
+		return
+	}
+
+	cfg.InfoColor().Printf("\n✓ Serving flows on http://%s\n", addr)
+	for _, name := range server.Flows() {
+		fmt.Printf("  POST /%s  (add ?stream=true to stream)\n", name)
+	}
+	fmt.Printf("  GET  /traces, /traces/{id}  (saved in %s)\n", server.TraceDir())
+	fmt.Println("Press Ctrl-C to stop")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx); err != nil {
+		slog.Error("Flow server failed", "error", err)
+		cfg.ErrorColor().Printf("Error: %v\n", err)
+	}
+}